@@ -0,0 +1,155 @@
+package spell
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	txt "github.com/hvlck/txt"
+)
+
+// Searches a trie for several queries in one walk. Queries are sorted, so a query shares a prefix with the one before
+// it, and the distances for that prefix are copied from the previous query's rows instead of being computed again.
+type batch_search struct {
+	queries []*lev_rows
+	// number of runes each query shares with the previous one
+	shared []int
+	limit  float64
+	// queries still within the limit at each depth, reused across siblings
+	alive [][]int
+	found [][]Correction
+}
+
+// Searches for every word within `limit` edit distances of each of `queries`, which must be sorted and unique,
+// returning the candidates of each query in the same order.
+func search_batch(n *txt.Node, queries []string, limit float64, m Metric, c *Costs) [][]Correction {
+	b := &batch_search{
+		queries: make([]*lev_rows, len(queries)),
+		shared:  make([]int, len(queries)),
+		limit:   limit,
+		found:   make([][]Correction, len(queries)),
+	}
+
+	all := make([]int, len(queries))
+	for k, s := range queries {
+		b.queries[k] = new_lev_rows(s, m, c)
+		b.found[k] = []Correction{}
+		all[k] = k
+		if k > 0 {
+			b.shared[k] = shared_prefix(queries[k-1], s)
+		}
+	}
+
+	if n != nil && len(queries) > 0 {
+		b.walk(n, 0, all)
+	}
+
+	return b.found
+}
+
+// Length of the common prefix of two strings, in runes.
+func shared_prefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+
+	// a rune split by the end of the prefix is not shared
+	for i > 0 && ((i < len(a) && !utf8.RuneStart(a[i])) || (i < len(b) && !utf8.RuneStart(b[i]))) {
+		i--
+	}
+
+	return utf8.RuneCountInString(a[:i])
+}
+
+// Walks the children of `n`, whose path has length `i`, for the queries in `alive`.
+func (b *batch_search) walk(n *txt.Node, i int, alive []int) {
+	if len(b.alive) <= i {
+		b.alive = append(b.alive, nil)
+	}
+
+	for rn, v := range n.Kids {
+		// end of a word
+		if v.Done && len(v.Kids) == 0 {
+			if i == 0 {
+				continue
+			}
+
+			for _, k := range alive {
+				if r := b.queries[k]; r.distance(i) <= b.limit {
+					b.found[k] = append(b.found[k], r.correction(i, v.Data))
+				}
+			}
+			continue
+		}
+
+		next := b.alive[i][:0]
+		prev := -1
+		for _, k := range alive {
+			var least float64
+			if prev == k-1 && k > 0 {
+				least = b.queries[k].push_shared(i, byte(rn), b.queries[k-1], b.shared[k])
+			} else {
+				least = b.queries[k].push(i, byte(rn))
+			}
+			prev = k
+
+			if least <= b.limit {
+				next = append(next, k)
+			}
+		}
+		b.alive[i] = next
+
+		if len(next) > 0 {
+			b.walk(v, i+1, next)
+		}
+	}
+}
+
+// Returns the `max` best corrections for each of `words`, keyed by word, as PartialMatch would for each of them.
+// Duplicate words are searched once, and every word is searched in the same walk of the trie, which is much faster
+// than searching them one by one.
+func Batch(n *txt.Node, words []string, target float64, max int) map[string][]Correction {
+	return batch(n, nil, words, target, max)
+}
+
+// Batch is the equivalent of the package-level Batch for the words in this dictionary, honoring its ignore and block
+// lists.
+func (d *Dict) Batch(words []string, target float64, max int) map[string][]Correction {
+	return batch(d.Node, d, words, target, max)
+}
+
+// Batch search of `n`, skipping words blocked by `d` and answering words ignored by it, if set.
+func batch(n *txt.Node, d *Dict, words []string, target float64, max int) map[string][]Correction {
+	res := map[string][]Correction{}
+	queries := []string{}
+	for _, w := range words {
+		if _, ok := res[w]; ok {
+			continue
+		}
+
+		if d != nil && d.ignores(w) {
+			res[w] = ignored(w)
+			continue
+		}
+
+		// placeholder, so duplicates are skipped
+		res[w] = nil
+		queries = append(queries, w)
+	}
+	sort.Strings(queries)
+
+	metric, costs := OSA, (*Costs)(nil)
+	if d != nil {
+		metric, costs = d.Metric, d.Costs
+	}
+
+	for k, f := range search_batch(n, queries, target, metric, costs) {
+		if d != nil {
+			f = d.Block.filter(f)
+		}
+
+		res[queries[k]] = rank(f, queries[k], target, max)
+	}
+
+	return res
+}
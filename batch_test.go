@@ -0,0 +1,72 @@
+package spell
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var batch_words = strings.Fields(`the speling of korrectud wurds in a documnt is teh same as speling them one by one
+recieve recieved recieving bycycle inconvient arrainged peotry peotryy wat tesk`)
+
+func TestBatch(t *testing.T) {
+	unique := map[string]bool{}
+	for _, w := range batch_words {
+		unique[w] = true
+	}
+
+	res := Batch(d.Node, batch_words, 2, 5)
+	if len(res) != len(unique) {
+		t.Fatalf("expected %v results, got %v", len(unique), len(res))
+	}
+
+	for _, w := range batch_words {
+		if want := PartialMatch(d.Node, w, 2, 5); !reflect.DeepEqual(res[w], want) {
+			t.Fatalf("%q: expected %v, got %v", w, want, res[w])
+		}
+	}
+
+	if r := Batch(d.Node, nil, 2, 5); len(r) != 0 {
+		t.Fatalf("expected no results, got %v", r)
+	}
+}
+
+func TestDictBatch(t *testing.T) {
+	dict, err := Load(strings.NewReader("duck\nduct\ndusk\n"), Words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict.Block.Add("duct")
+	dict.Ignore.Add("dxck")
+
+	res := dict.Batch([]string{"duck", "dxck", "duck", "dusc"}, 1, 5)
+	for w, r := range res {
+		if want := dict.PartialMatch(w, 1, 5); !reflect.DeepEqual(r, want) {
+			t.Fatalf("%q: expected %v, got %v", w, want, r)
+		}
+	}
+
+	if len(res) != 3 {
+		t.Fatalf("expected 3 results, got %v", res)
+	}
+}
+
+// go test -bench Batch -benchmem
+//
+//	BenchmarkBatch/batch    40    31675417 ns/op    4801830 B/op     90742 allocs/op
+//	BenchmarkBatch/loop     26    50609697 ns/op    5348449 B/op    101381 allocs/op
+func BenchmarkBatch(b *testing.B) {
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Batch(d.Node, batch_words, 2, 5)
+		}
+	})
+
+	b.Run("loop", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, w := range batch_words {
+				PartialMatch(d.Node, w, 2, 5)
+			}
+		}
+	})
+}
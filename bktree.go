@@ -0,0 +1,340 @@
+package spell
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+	"unicode/utf8"
+)
+
+// BKTree is a Burkhard-Keller tree over the words of a dictionary. Each word is stored below the word it was first
+// compared with, on the edge labelled with their distance, so a search only follows edges whose label is within its
+// radius of the distance to the current word, and skips most of the dictionary. It uses far less memory than a
+// SymSpell index, at the cost of slower searches.
+//
+// The triangle inequality that makes this work does not hold for the optimal string alignment distance used by the
+// trie search, so the tree is built with the unrestricted Damerau-Levenshtein distance, which does satisfy it and is
+// never larger than the OSA or Levenshtein distance. Searches find every word within their limit by that distance,
+// then keep those within it by the dictionary's metric, so they return the same words as the dictionary. Only
+// dictionaries using the OSA or Levenshtein metric can be indexed.
+//
+// Like SymSpell, the tree is a snapshot of the dictionary and its metric, honors its ignore and block lists, and is
+// safe for concurrent use.
+type BKTree struct {
+	dict *Dict
+	root *bk_node
+	size int
+	// metric searches are filtered and measured with
+	metric Metric
+}
+
+type bk_node struct {
+	word string
+	// frequency data of the word
+	data []byte
+	// distance to the parent word
+	dist int
+	kids []*bk_node
+}
+
+// NewBKTree builds a BK-tree from the words of `d`. ErrUnsupportedMetric is returned if `d` uses a metric other than
+// OSA or Levenshtein.
+func NewBKTree(d *Dict) (*BKTree, error) {
+	if d.Metric != OSA && d.Metric != Levenshtein {
+		return nil, ErrUnsupportedMetric
+	}
+
+	t := &BKTree{dict: d, metric: d.Metric}
+	sc := &bk_scratch{}
+	walk_terminals(d.Node, nil, func(word []byte, data []byte) {
+		t.insert(string(word), data, sc)
+	})
+
+	return t, nil
+}
+
+func (t *BKTree) insert(word string, data []byte, s *bk_scratch) {
+	t.size++
+	if t.root == nil {
+		t.root = &bk_node{word: word, data: data}
+		return
+	}
+
+	n := t.root
+	w := []rune(word)
+	var buf []rune
+outer:
+	for {
+		buf = append_runes(buf[:0], n.word)
+		d := s.distance(Damerau, w, buf)
+		if d == 0 {
+			// duplicates are impossible in a trie
+			return
+		}
+
+		for _, k := range n.kids {
+			if k.dist == d {
+				n = k
+				continue outer
+			}
+		}
+
+		n.kids = append(n.kids, &bk_node{word: word, data: data, dist: d})
+		return
+	}
+}
+
+// Len returns the number of words in the tree.
+func (t *BKTree) Len() int {
+	return t.size
+}
+
+// Appends the runes of `s` to `buf`.
+func append_runes(buf []rune, s string) []rune {
+	for _, r := range s {
+		buf = append(buf, r)
+	}
+
+	return buf
+}
+
+// Scratch space for computing distances between words without allocating.
+type bk_scratch struct {
+	// (len(a)+2) × (len(b)+2) distances, with a border row and column for unrestricted transpositions
+	rows []int
+	// last row of `a` where each rune was seen, for ASCII runes and the others
+	ascii [utf8.RuneSelf]int
+	last  map[rune]int
+}
+
+// Last row of `a` where `c` was seen, 0 if none.
+func (s *bk_scratch) seen(c rune) int {
+	if c >= 0 && c < utf8.RuneSelf {
+		return s.ascii[c]
+	}
+
+	return s.last[c]
+}
+
+// Levenshtein, OSA or unrestricted Damerau distance between two words.
+func (s *bk_scratch) distance(m Metric, a, b []rune) int {
+	w := len(b) + 2
+	if n := (len(a) + 2) * w; cap(s.rows) < n {
+		s.rows = make([]int, n)
+	}
+	// h[(i+1)*w + j+1] is the distance between the first `i` runes of `a` and the first `j` runes of `b`
+	h := s.rows[:(len(a)+2)*w]
+
+	inf := len(a) + len(b)
+	h[0] = inf
+	for i := 0; i <= len(a); i++ {
+		h[(i+1)*w] = inf
+		h[(i+1)*w+1] = i
+	}
+	for j := 0; j <= len(b); j++ {
+		h[j+1] = inf
+		h[w+j+1] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		// last column of `b` matching a[i-1]
+		db := 0
+		for j := 1; j <= len(b); j++ {
+			i1, j1 := s.seen(b[j-1]), db
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+				db = j
+			}
+
+			v := min(h[i*w+j]+cost, h[(i+1)*w+j]+1, h[i*w+j+1]+1)
+			switch m {
+			case OSA:
+				if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+					v = min(v, h[(i-1)*w+j-1]+1)
+				}
+			case Damerau:
+				// transposition of a[i1-1] and b[j-1], deleting and inserting everything between them
+				v = min(v, h[i1*w+j1]+(i-i1-1)+1+(j-j1-1))
+			}
+
+			h[(i+1)*w+j+1] = v
+		}
+
+		if m == Damerau {
+			if c := a[i-1]; c >= 0 && c < utf8.RuneSelf {
+				s.ascii[c] = i
+			} else {
+				if s.last == nil {
+					s.last = map[rune]int{}
+				}
+				s.last[c] = i
+			}
+		}
+	}
+
+	// forget the rows seen, for the next call
+	for _, c := range a {
+		if c >= 0 && c < utf8.RuneSelf {
+			s.ascii[c] = 0
+		} else if s.last != nil {
+			delete(s.last, c)
+		}
+	}
+
+	return h[(len(a)+1)*w+len(b)+1]
+}
+
+// A word found by a search, and its distance to the query under the tree's metric.
+type bk_result struct {
+	n    *bk_node
+	dist int
+}
+
+// Orders results by distance, then by word.
+func (r bk_result) less(o bk_result) bool {
+	if r.dist != o.dist {
+		return r.dist < o.dist
+	}
+	return r.n.word < o.n.word
+}
+
+func (r bk_result) correction(m Metric, s string) Correction {
+	c := new_correction(r.n.word, operations(m, nil, r.n.word, s), r.n.data)
+	c.metric = m
+	return c
+}
+
+// Radius returns every word within `r` edits of `s` under the dictionary's metric, closest first. Blocked words are
+// skipped.
+func (t *BKTree) Radius(s string, r int) []Correction {
+	if t.root == nil || r < 0 {
+		return []Correction{}
+	}
+
+	found := []bk_result{}
+	q := []rune(s)
+	sc := bk_scratch{}
+	var buf []rune
+	stack := []*bk_node{t.root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		buf = append_runes(buf[:0], n.word)
+		d := sc.distance(Damerau, q, buf)
+		// the metric's distance is never smaller, so it only needs computing for words within the radius
+		if d <= r && !t.dict.Block.Match(n.word) {
+			if md := sc.distance(t.metric, q, buf); md <= r {
+				found = append(found, bk_result{n, md})
+			}
+		}
+
+		for _, k := range n.kids {
+			if abs(k.dist-d) <= r {
+				stack = append(stack, k)
+			}
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].less(found[j])
+	})
+
+	res := make([]Correction, len(found))
+	for i, v := range found {
+		res[i] = v.correction(t.metric, s)
+	}
+
+	return res
+}
+
+// Max-heap of the nearest words found so far, furthest first.
+type bk_nearest []bk_result
+
+func (h bk_nearest) Len() int            { return len(h) }
+func (h bk_nearest) Less(i, j int) bool  { return h[j].less(h[i]) }
+func (h bk_nearest) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *bk_nearest) Push(x interface{}) { *h = append(*h, x.(bk_result)) }
+func (h *bk_nearest) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// Nearest returns the `k` words closest to `s` under the dictionary's metric, closest first. Words at the same distance are
+// ordered by byte order, so the result does not depend on the shape of the tree. Blocked words are skipped.
+func (t *BKTree) Nearest(s string, k int) []Correction {
+	if t.root == nil || k <= 0 {
+		return []Correction{}
+	}
+
+	h := &bk_nearest{}
+	q := []rune(s)
+	sc := bk_scratch{}
+	var buf []rune
+	// distance of the furthest word kept, once k words have been found
+	radius := math.MaxInt
+	stack := []*bk_node{t.root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		buf = append_runes(buf[:0], n.word)
+		d := sc.distance(Damerau, q, buf)
+		if d <= radius && !t.dict.Block.Match(n.word) {
+			if md := sc.distance(t.metric, q, buf); md <= radius {
+				heap.Push(h, bk_result{n, md})
+				if h.Len() > k {
+					heap.Pop(h)
+				}
+
+				if h.Len() == k {
+					radius = (*h)[0].dist
+				}
+			}
+		}
+
+		for _, c := range n.kids {
+			if radius == math.MaxInt || abs(c.dist-d) <= radius {
+				stack = append(stack, c)
+			}
+		}
+	}
+
+	res := make([]Correction, h.Len())
+	for i := len(res) - 1; i >= 0; i-- {
+		res[i] = heap.Pop(h).(bk_result).correction(t.metric, s)
+	}
+
+	return res
+}
+
+// Candidates returns all words within `limit` edits of `s` under the dictionary's metric. Blocked words are skipped.
+func (t *BKTree) Candidates(s string, limit float64) []Correction {
+	if limit < 0 {
+		return []Correction{}
+	}
+
+	return t.Radius(s, int(math.Floor(limit)))
+}
+
+// Version returns a number that changes whenever the ignore or block lists of the dictionary are edited, like
+// SymSpell.Version.
+func (t *BKTree) Version() uint64 {
+	return t.dict.Ignore.version() + t.dict.Block.version()
+}
+
+func (t *BKTree) ignores(s string) bool {
+	return t.dict.Ignore.Match(s)
+}
+
+func (t *BKTree) blocks(s string) bool {
+	return t.dict.Block.Match(s)
+}
+
+// PartialMatch returns the `max` best corrections for `s` within `target` edit distances, like the dictionary's.
+func (t *BKTree) PartialMatch(s string, target float64, max int) []Correction {
+	return Match(t, s, target, max)
+}
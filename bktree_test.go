@@ -0,0 +1,195 @@
+package spell
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	txt "github.com/hvlck/txt"
+)
+
+var bktree = must(NewBKTree(d))
+
+// Every word with its OSA distance to `s`, sorted by distance and word.
+func bk_reference(s string) []struct {
+	word string
+	dist int
+} {
+	all := []struct {
+		word string
+		dist int
+	}{}
+	walk_terminals(d.Node, nil, func(word []byte, _ []byte) {
+		all = append(all, struct {
+			word string
+			dist int
+		}{string(word), int(OSA.Distance(string(word), s))})
+	})
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].dist != all[j].dist {
+			return all[i].dist < all[j].dist
+		}
+		return all[i].word < all[j].word
+	})
+
+	return all
+}
+
+func TestBKTree(t *testing.T) {
+	if bktree.Len() != len(strings.Fields(string(dict_file))) {
+		t.Fatalf("expected %v words, got %v", len(strings.Fields(string(dict_file))), bktree.Len())
+	}
+
+	for _, s := range []string{"wat", "tesk", "korrectud", "peotryy", "abcdefghijkl"} {
+		ref := bk_reference(s)
+
+		for _, r := range []int{0, 1, 2} {
+			want := []string{}
+			for _, v := range ref {
+				if v.dist <= r {
+					want = append(want, v.word)
+				}
+			}
+
+			got := []string{}
+			for _, v := range bktree.Radius(s, r) {
+				got = append(got, v.Word)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("%q within %v: expected %v, got %v", s, r, want, got)
+			}
+		}
+
+		for _, k := range []int{1, 5, 20} {
+			got := []string{}
+			for _, v := range bktree.Nearest(s, k) {
+				got = append(got, v.Word)
+			}
+
+			want := []string{}
+			for _, v := range ref[:k] {
+				want = append(want, v.word)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("%v nearest to %q: expected %v, got %v", k, s, want, got)
+			}
+		}
+	}
+}
+
+func TestBKTreeTranspositions(t *testing.T) {
+	for _, s := range []string{"teh", "recieve", "liek"} {
+		for _, limit := range []float64{1, 2} {
+			got, want := bktree.Candidates(s, limit), d.Candidates(s, limit)
+			if !reflect.DeepEqual(candidate_words(got), candidate_words(want)) {
+				t.Fatalf("%q within %v: expected %v, got %v", s, limit, candidate_words(want), candidate_words(got))
+			}
+		}
+	}
+
+	if r := bktree.PartialMatch("teh", 1, 5); len(r) == 0 || r[0].Word != "the" {
+		t.Fatalf("expected the for teh, got %v", r)
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	sc := bk_scratch{}
+	for n := 0; n < 5000; n++ {
+		a, b := random_word(rnd, "abcé", 7), random_word(rnd, "abcé", 7)
+		for _, m := range []Metric{Levenshtein, OSA, Damerau} {
+			if got, want := sc.distance(m, []rune(a), []rune(b)), int(m.Distance(a, b)); got != want {
+				t.Fatalf("%v(%q, %q): expected %v, got %v", m, a, b, want, got)
+			}
+		}
+	}
+}
+
+func TestBKTreeMetric(t *testing.T) {
+	dict, err := Load(strings.NewReader("cat\nact\ncart\nscat\ntac\n"), Words)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// under levenshtein, the tree finds the same words as the dictionary, with the same distances
+	dict.Metric = Levenshtein
+	tree := must(NewBKTree(dict))
+	for _, s := range []string{"cat", "atc", "cta"} {
+		for _, limit := range []float64{1, 2} {
+			got, want := tree.Candidates(s, limit), dict.Candidates(s, limit)
+			sort.Slice(want, func(i, j int) bool { return want[i].Word < want[j].Word })
+			sort.Slice(got, func(i, j int) bool { return got[i].Word < got[j].Word })
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("%q within %v: expected %v, got %v", s, limit, candidate_words(want), candidate_words(got))
+			}
+		}
+	}
+
+	for _, m := range []Metric{Damerau, Keyboard, JaroWinkler, Dice, Jaccard} {
+		dict.Metric = m
+		if _, err := NewBKTree(dict); err != ErrUnsupportedMetric {
+			t.Fatalf("%v: expected ErrUnsupportedMetric, got %v", m, err)
+		}
+	}
+}
+
+func TestBKTreeLists(t *testing.T) {
+	dict, err := Load(strings.NewReader("cat\ncar\ncart\ndog\n"), Words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict.Block.Add("car")
+	dict.Ignore.Add("cst")
+
+	tree := must(NewBKTree(dict))
+	if r := tree.Nearest("cat", 10); len(r) != 3 || r[0].Word != "cat" || r[1].Word != "cart" || r[2].Word != "dog" {
+		t.Fatalf("expected cat, cart and dog, got %v", r)
+	}
+
+	if r := tree.PartialMatch("cst", 1, 5); len(r) != 1 || r[0].Word != "cst" {
+		t.Fatalf("expected ignored word to be returned as-is, got %v", r)
+	}
+
+	if r := must(NewBKTree(&Dict{Node: txt.NewTrie()})).Nearest("cat", 1); len(r) != 0 {
+		t.Fatalf("expected no words in an empty tree, got %v", r)
+	}
+}
+
+// go test -bench BKTree -benchmem
+//
+//	BenchmarkBKTree/wat                   399     3239881 ns/op    211320 B/op     2569 allocs/op
+//	BenchmarkBKTree/wat/linear           1108     1379532 ns/op    306704 B/op     3074 allocs/op
+//	BenchmarkBKTree/korrectud              68    17124973 ns/op      5816 B/op       24 allocs/op
+//	BenchmarkBKTree/korrectud/linear     1096     1394520 ns/op      4240 B/op       55 allocs/op
+//	BenchmarkBKTree/nearest                16    69714235 ns/op     42216 B/op     1509 allocs/op
+//	BenchmarkNewBKTree                      2   703526811 ns/op  14285236 B/op   342863 allocs/op
+func BenchmarkBKTree(b *testing.B) {
+	for _, s := range []string{"wat", "korrectud"} {
+		b.Run(s, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bktree.Radius(s, 2)
+			}
+		})
+
+		b.Run(s+"/linear", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Correct(s, 2)
+			}
+		})
+	}
+
+	b.Run("nearest", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			bktree.Nearest("korrectud", 10)
+		}
+	})
+}
+
+func BenchmarkNewBKTree(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		must(NewBKTree(d))
+	}
+}
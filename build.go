@@ -0,0 +1,170 @@
+package spell
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	txt "github.com/hvlck/txt"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Counter tokenizes text corpora and counts how often each word is used, to build frequency dictionaries.
+// A word is a run of letters (and combining marks), optionally joined by apostrophes or hyphens, such as `don't` or
+// `well-known`; numbers and punctuation separate words.
+type Counter struct {
+	// Lowercase every word before counting it.
+	Lower bool
+	// Normalize every word to Unicode NFC, so precomposed and decomposed forms (e.g. `é` and `e` + `◌́`) are counted
+	// as the same word.
+	NFC bool
+	// Words shorter than this many characters are not counted.
+	MinLength int
+
+	counts map[string]uint64
+}
+
+// NewCounter creates a Counter that lowercases and NFC-normalizes words.
+func NewCounter() *Counter {
+	return &Counter{Lower: true, NFC: true, counts: map[string]uint64{}}
+}
+
+// Splits words out of text, for bufio.Scanner.
+func scan_tokens(data []byte, atEOF bool) (int, []byte, error) {
+	is_letter := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.Is(unicode.Mn, r)
+	}
+
+	// skip leading separators
+	start := 0
+	for start < len(data) {
+		r, w := utf8.DecodeRune(data[start:])
+		if r == utf8.RuneError && w == 1 && !atEOF && !utf8.FullRune(data[start:]) {
+			return start, nil, nil
+		}
+
+		if is_letter(r) {
+			break
+		}
+		start += w
+	}
+
+	for i := start; i < len(data); {
+		if !atEOF && !utf8.FullRune(data[i:]) {
+			break
+		}
+
+		r, w := utf8.DecodeRune(data[i:])
+		if is_letter(r) {
+			i += w
+			continue
+		}
+
+		// joiners are only part of a word if followed by another letter
+		if r == '\'' || r == '’' || r == '-' {
+			if !atEOF && !utf8.FullRune(data[i+w:]) {
+				break
+			}
+
+			if next, _ := utf8.DecodeRune(data[i+w:]); is_letter(next) {
+				i += w
+				continue
+			}
+		}
+
+		return i + w, data[start:i], nil
+	}
+
+	if atEOF && start < len(data) {
+		return len(data), data[start:], nil
+	}
+
+	// request more data
+	return start, nil, nil
+}
+
+// Add counts every word read from `r`.
+func (c *Counter) Add(r io.Reader) error {
+	if c.counts == nil {
+		c.counts = map[string]uint64{}
+	}
+
+	scn := bufio.NewScanner(r)
+	scn.Split(scan_tokens)
+	for scn.Scan() {
+		w := strings.ReplaceAll(scn.Text(), "’", "'")
+		if c.NFC {
+			w = norm.NFC.String(w)
+		}
+
+		if c.Lower {
+			w = strings.ToLower(w)
+		}
+
+		if utf8.RuneCountInString(w) < c.MinLength || !valid_word(w) {
+			continue
+		}
+
+		c.counts[w]++
+	}
+
+	return scn.Err()
+}
+
+// A word and the number of times it was counted.
+type WordCount struct {
+	Word  string
+	Count uint64
+}
+
+// Counts returns every word counted at least `min` times, most frequent first. Words with equal counts are sorted in
+// byte order.
+func (c *Counter) Counts(min uint64) []WordCount {
+	r := make([]WordCount, 0, len(c.counts))
+	for w, n := range c.counts {
+		if n >= min {
+			r = append(r, WordCount{Word: w, Count: n})
+		}
+	}
+
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Count != r[j].Count {
+			return r[i].Count > r[j].Count
+		}
+		return r[i].Word < r[j].Word
+	})
+
+	return r
+}
+
+// WriteCSV writes every word counted at least `min` times as `word,count` lines, which can be loaded with the CSV
+// loader.
+func (c *Counter) WriteCSV(w io.Writer, min uint64) error {
+	bw := bufio.NewWriter(w)
+	for _, v := range c.Counts(min) {
+		bw.WriteString(v.Word)
+		bw.WriteByte(',')
+		bw.WriteString(strconv.FormatUint(v.Count, 10))
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Dict builds a dictionary of every word counted at least `min` times, with their counts as frequencies.
+func (c *Counter) Dict(min uint64) *Dict {
+	d := &Dict{Node: txt.NewTrie()}
+	for _, v := range c.Counts(min) {
+		// counted words are always valid
+		d.insert(v.Word, float64(v.Count))
+	}
+	d.Normalize()
+
+	return d
+}
@@ -0,0 +1,51 @@
+package spell
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestCounter(t *testing.T) {
+	c := NewCounter()
+	c.MinLength = 2
+
+	input := "The cat's hat -- the CAT, the well-known cafe\u0301 (café) in 1999 isn't 'quoted' a"
+	// one byte at a time, to exercise tokens split across reads
+	if err := c.Add(iotest.OneByteReader(strings.NewReader(input))); err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, v := range c.Counts(1) {
+		got = append(got, v.Word+":"+string(rune('0'+v.Count)))
+	}
+
+	want := "the:3 café:2 cat:1 cat's:1 hat:1 in:1 isn't:1 quoted:1 well-known:1"
+	if strings.Join(got, " ") != want {
+		t.Fatalf("expected %v, got %v", want, strings.Join(got, " "))
+	}
+
+	buf := bytes.Buffer{}
+	if err := c.WriteCSV(&buf, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != "the,3\ncafé,2\n" {
+		t.Fatalf("unexpected csv %q", buf.String())
+	}
+
+	d, err := Load(&buf, CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count, _, ok := d.Frequency("café"); !ok || count != 2 {
+		t.Fatalf("expected csv output to load, got %v", count)
+	}
+
+	if count, _, _ := c.Dict(3).Frequency("the"); count != 3 || c.Dict(3).Contains("café") {
+		t.Fatal("expected Dict to apply the minimum count")
+	}
+}
@@ -0,0 +1,134 @@
+package spell
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache keeps the results of recent searches of a source, so repeated misspellings are only searched once. Results
+// are keyed by the word, distance limit, number of results and the version of the source; when the source's version
+// changes (e.g. a word is inserted into a *Dict, its block list is edited, or its Metric is changed), every cached
+// result is discarded.
+// Sources without a Version method are assumed never to change.
+//
+// Once the cache holds `capacity` results, the least recently used is evicted. A Cache is safe for concurrent use.
+type Cache struct {
+	src      CandidateSource
+	capacity int
+
+	mu      sync.Mutex
+	entries map[cache_key]*list.Element
+	// most recently used first
+	order *list.List
+	// version of the source the entries were found with
+	version uint64
+	stats   CacheStats
+}
+
+type cache_key struct {
+	word    string
+	limit   float64
+	max     int
+	version uint64
+}
+
+type cache_entry struct {
+	key cache_key
+	res []Correction
+}
+
+// CacheStats counts the lookups of a Cache.
+type CacheStats struct {
+	// Searches answered from the cache.
+	Hits uint64
+	// Searches that were not cached, including those whose result had been discarded.
+	Misses uint64
+	// Results evicted to make room for newer ones.
+	Evictions uint64
+	// Results currently cached.
+	Len int
+}
+
+// NewCache creates a cache of up to `capacity` results in front of `src`.
+func NewCache(src CandidateSource, capacity int) *Cache {
+	return &Cache{src: src, capacity: capacity, entries: map[cache_key]*list.Element{}, order: list.New()}
+}
+
+// Version of the source, 0 if it never changes.
+func (c *Cache) source_version() uint64 {
+	if v, ok := c.src.(versioned); ok {
+		return v.Version()
+	}
+
+	return 0
+}
+
+// PartialMatch returns the same corrections as Match(src, s, target, max), from the cache if possible. Callers own
+// the returned slice.
+func (c *Cache) PartialMatch(s string, target float64, max int) []Correction {
+	key := cache_key{word: s, limit: target, max: max, version: c.source_version()}
+
+	c.mu.Lock()
+	if key.version != c.version {
+		c.purge()
+		c.version = key.version
+	}
+
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		c.stats.Hits++
+		res := append([]Correction{}, e.Value.(*cache_entry).res...)
+		c.mu.Unlock()
+		return res
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	// searched without holding the lock; concurrent misses for the same word search it more than once
+	res := Match(c.src, s, target, max)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the source changed during the search, or another search already stored the result
+	if key.version != c.version || c.capacity <= 0 {
+		return res
+	}
+
+	if _, ok := c.entries[key]; ok {
+		return res
+	}
+
+	c.entries[key] = c.order.PushFront(&cache_entry{key: key, res: append([]Correction{}, res...)})
+	for c.order.Len() > c.capacity {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.entries, last.Value.(*cache_entry).key)
+		c.stats.Evictions++
+	}
+
+	return res
+}
+
+// Stats returns the cache's statistics.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.stats
+	s.Len = c.order.Len()
+	return s
+}
+
+// Purge discards every cached result. Statistics are kept.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.purge()
+}
+
+func (c *Cache) purge() {
+	c.entries = map[cache_key]*list.Element{}
+	c.order.Init()
+}
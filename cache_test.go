@@ -0,0 +1,143 @@
+package spell
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	dict, err := Load(strings.NewReader("the\nreceive\nten\ntea\n"), Words)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewCache(dict, 2)
+	first := c.PartialMatch("teh", 1, 5)
+	if !reflect.DeepEqual(first, Match(dict, "teh", 1, 5)) {
+		t.Fatalf("expected cached results to match an uncached search, got %v", first)
+	}
+
+	// results are copies
+	first[0].Word = "changed"
+	if r := c.PartialMatch("teh", 1, 5); r[0].Word == "changed" {
+		t.Fatal("cached results modified through a returned slice")
+	}
+
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 1 || s.Len != 1 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	// different limits and maximums are cached separately
+	c.PartialMatch("teh", 2, 5)
+	c.PartialMatch("teh", 1, 5)
+	c.PartialMatch("recieve", 2, 5)
+	if s := c.Stats(); s.Hits != 2 || s.Misses != 3 || s.Evictions != 1 || s.Len != 2 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	// the least recently used result, teh within 2, was evicted
+	c.PartialMatch("teh", 2, 5)
+	if s := c.Stats(); s.Misses != 4 {
+		t.Fatalf("expected a miss for an evicted result, got %+v", s)
+	}
+
+	// changes to the dictionary discard cached results
+	for _, change := range []func(){
+		func() { dict.Insert("teh", nil) },
+		func() { dict.Block.Add("teh") },
+		func() { dict.Remove("teh") },
+		func() { dict.Ignore.Add("teh") },
+	} {
+		c.PartialMatch("teh", 1, 5)
+		before := c.Stats().Misses
+		change()
+
+		r := c.PartialMatch("teh", 1, 5)
+		if c.Stats().Misses != before+1 {
+			t.Fatalf("expected a miss after a change, got %+v", c.Stats())
+		}
+
+		if want := Match(dict, "teh", 1, 5); !reflect.DeepEqual(r, want) {
+			t.Fatalf("expected %v after a change, got %v", want, r)
+		}
+	}
+}
+
+func TestCacheMetric(t *testing.T) {
+	dict, _ := Load(strings.NewReader("abc\nwxyz\n"), Words)
+	c := NewCache(dict, 10)
+	if r := c.PartialMatch("ca", 2, 5); len(r) != 0 {
+		t.Fatalf("expected nothing within 2 osa edits of ca, got %v", r)
+	}
+
+	dict.Metric = Damerau
+	if r := c.PartialMatch("ca", 2, 5); len(r) != 1 || r[0].Word != "abc" {
+		t.Fatalf("expected abc after switching to damerau, got %v", r)
+	}
+
+	dict.Metric = Keyboard
+	if r := c.PartialMatch("wxzy", .5, 5); len(r) != 0 {
+		t.Fatalf("expected nothing within half an edit of wxzy, got %v", r)
+	}
+
+	dict.Costs = &Costs{Insertion: 1, Deletion: 1, Transposition: .25, Substitution: .5, MaxSubstitution: 1}
+	if r := c.PartialMatch("wxzy", .5, 5); len(r) != 1 || r[0].Word != "wxyz" {
+		t.Fatalf("expected wxyz after making transpositions cheaper, got %v", r)
+	}
+
+	var buf bytes.Buffer
+	if _, err := dict.WriteMapped(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	m, _ := NewMappedDict(buf.Bytes())
+	mc := NewCache(m, 10)
+	mc.PartialMatch("ca", 2, 5)
+	m.Metric = Damerau
+	if r := mc.PartialMatch("ca", 2, 5); len(r) != 1 || r[0].Word != "abc" {
+		t.Fatalf("expected abc after switching a mapped dictionary to damerau, got %v", r)
+	}
+}
+
+func TestCacheLayers(t *testing.T) {
+	p := NewPersonal()
+	l := NewLayers(Layer{Name: "personal", Source: p})
+	c := NewCache(l, 10)
+
+	if r := c.PartialMatch("grafana", 0, 1); len(r) != 0 {
+		t.Fatalf("expected no results, got %v", r)
+	}
+
+	p.Add("grafana")
+	if r := c.PartialMatch("grafana", 0, 1); len(r) != 1 {
+		t.Fatalf("expected the new word to be found, got %v", r)
+	}
+
+	before := l.Version()
+	l.Remove("personal")
+	if l.Version() == before {
+		t.Fatal("expected removing a layer to change the version")
+	}
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	c := NewCache(d, 4)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for _, w := range []string{"teh", "recieve", "wat", "speling", "teh"} {
+				c.PartialMatch(w, 1, 5)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if s := c.Stats(); s.Hits+s.Misses != 40 || s.Len > 4 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
@@ -2,42 +2,273 @@ package main
 
 import (
 	"bufio"
-	"bytes"
+	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"spell"
+	"strings"
 	"time"
 
 	"github.com/cheynewallace/tabby"
-	"github.com/hvlck/txt"
 )
 
-type Dictionary struct {
-	trie *txt.Node
+var loaders = map[string]spell.Loader{
+	"words":   spell.Words,
+	"csv":     spell.CSV,
+	"tsv":     spell.TSV,
+	"unigram": spell.Unigram,
 }
 
-func main() {
-	s := time.Now()
+// Loads the dictionary at `path` in the given format, or the embedded dictionary if `path` is empty.
+func loadDict(path, format string) (*spell.Dict, error) {
+	if len(path) == 0 {
+		return spell.NewDict(), nil
+	}
+
+	l, ok := loaders[format]
+	if !ok && format != "hunspell" && format != "snapshot" {
+		return nil, fmt.Errorf("unknown dictionary format %q", format)
+	}
 
-	b, err := os.ReadFile("../data/final.txt")
+	f, err := os.Open(path)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	defer f.Close()
+
+	if format == "snapshot" {
+		return spell.ReadSnapshot(f)
+	}
+
+	if format == "hunspell" {
+		// affix file sits next to the word list
+		aff, err := os.Open(strings.TrimSuffix(path, filepath.Ext(path)) + ".aff")
+		if err != nil {
+			return nil, err
+		}
+		defer aff.Close()
+
+		return spell.LoadHunspell(f, aff)
 	}
-	t := txt.NewTrie()
 
-	lines := bytes.Split(b, []byte("\n"))
-	for _, v := range lines {
-		if len(v) > 0 {
-			r := bytes.Split(v, []byte(","))
-			w := string(r[0])
-			t.Insert(w, r[1])
+	return spell.Load(f, l)
+}
+
+// Counts the words in every file under `paths` (recursing into directories).
+func count(c *spell.Counter, paths []string) error {
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, e fs.DirEntry, err error) error {
+			if err != nil || !e.Type().IsRegular() {
+				return err
+			}
+
+			f, err := os.Open(path)
 			if err != nil {
-				panic(err)
+				return err
 			}
+			defer f.Close()
+
+			return c.Add(f)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// spell build: builds a frequency dictionary from text corpora.
+func build(args []string) error {
+	fl := flag.NewFlagSet("build", flag.ExitOnError)
+	fl.Usage = func() {
+		fmt.Fprintln(fl.Output(), "usage: spell build [flags] corpus...")
+		fl.PrintDefaults()
+	}
+
+	out := fl.String("o", "", "dictionary file to write, as word,count lines (default stdout)")
+	snapshot := fl.String("snapshot", "", "also write a binary snapshot of the dictionary to this file")
+	mapped := fl.String("mapped", "", "also write a memory-mapped trie image of the dictionary to this file")
+	min := fl.Uint64("min-count", 1, "minimum number of occurrences for a word to be included")
+	length := fl.Int("min-length", 1, "minimum length of a word, in characters")
+	lower := fl.Bool("lower", true, "lowercase words")
+	nfc := fl.Bool("nfc", true, "normalize words to Unicode NFC")
+	fl.Parse(args)
+
+	if fl.NArg() == 0 {
+		fl.Usage()
+		os.Exit(2)
+	}
+
+	c := spell.NewCounter()
+	c.Lower = *lower
+	c.NFC = *nfc
+	c.MinLength = *length
+
+	if err := count(c, fl.Args()); err != nil {
+		return err
+	}
+
+	var err error
+	if len(*out) > 0 {
+		err = create(*out, func(w io.Writer) error {
+			return c.WriteCSV(w, *min)
+		})
+	} else {
+		err = c.WriteCSV(os.Stdout, *min)
+	}
+
+	var d *spell.Dict
+	if len(*snapshot) > 0 || len(*mapped) > 0 {
+		d = c.Dict(*min)
+	}
+
+	if err == nil && len(*snapshot) > 0 {
+		err = create(*snapshot, func(w io.Writer) error {
+			_, err := d.WriteTo(w)
+			return err
+		})
+	}
+
+	if err == nil && len(*mapped) > 0 {
+		err = create(*mapped, func(w io.Writer) error {
+			_, err := d.WriteMapped(w)
+			return err
+		})
+	}
+
+	return err
+}
+
+// Creates the file at `path` and writes it with `write`.
+func create(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// Describes the edits turning the query into a correction.
+func edits(c spell.Correction) string {
+	e := []string{}
+	for _, v := range c.Edits() {
+		e = append(e, v.String())
+	}
+
+	return strings.Join(e, ", ")
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "build" {
+		if err := build(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	path := flag.String("dict", "", "dictionary file to load instead of the embedded word list")
+	personal := flag.String("personal", "", "personal word list; entering +word adds a word to it and -word removes one")
+	format := flag.String("format", "csv", "format of the dictionary file: words, csv, tsv, unigram, snapshot, mapped (trie index only) or hunspell (.dic, with the .aff alongside)")
+	index := flag.String("index", "trie", "index searched for corrections: trie, symspell (distance 2) or bktree")
+	parallel := flag.Int("parallel", 1, "number of goroutines searching the trie; -1 uses every CPU")
+	metric := flag.String("metric", "osa", "metric used by the trie: osa, levenshtein, damerau, keyboard, jaro-winkler, dice or jaccard")
+	flag.Parse()
+
+	s := time.Now()
+
+	var m spell.Metric
+	switch *metric {
+	case "osa":
+		m = spell.OSA
+	case "levenshtein":
+		m = spell.Levenshtein
+	case "damerau":
+		m = spell.Damerau
+	case "keyboard":
+		m = spell.Keyboard
+	case "jaro-winkler":
+		m = spell.JaroWinkler
+	case "dice":
+		m = spell.Dice
+	case "jaccard":
+		m = spell.Jaccard
+	default:
+		fmt.Fprintf(os.Stderr, "unknown metric %q\n", *metric)
+		os.Exit(1)
+	}
+
+	var src spell.CandidateSource
+	if *format == "mapped" {
+		// mapped images are searched in place, so they cannot be indexed
+		if *index != "trie" {
+			fmt.Fprintf(os.Stderr, "%v index: mapped dictionaries only support the trie index\n", *index)
+			os.Exit(1)
 		}
+
+		md, err := spell.OpenMapped(*path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer md.Close()
+
+		md.Metric = m
+		src = md
+	} else {
+		d, err := loadDict(*path, *format)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		d.Parallelism = *parallel
+		d.Metric = m
+
+		switch *index {
+		case "trie":
+			src = d
+		case "symspell":
+			src, err = spell.NewSymSpell(d, 2)
+		case "bktree":
+			src, err = spell.NewBKTree(d)
+		default:
+			fmt.Fprintf(os.Stderr, "unknown index %q\n", *index)
+			os.Exit(1)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v index: %v\n", *index, err)
+			os.Exit(1)
+		}
+	}
+
+	var err error
+
+	var p *spell.Personal
+	if len(*personal) > 0 {
+		p, err = spell.OpenPersonal(*personal)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		src = spell.NewLayers(
+			spell.Layer{Name: "dictionary", Source: src},
+			spell.Layer{Name: "personal", Source: p, Priority: 1},
+		)
 	}
-	d := Dictionary{trie: t}
 
 	fmt.Printf("loaded dictionary in %vms\n", time.Since(s).Milliseconds())
 	scn := bufio.NewScanner(os.Stdin)
@@ -51,15 +282,28 @@ func main() {
 
 		ln := scn.Text()
 
+		if p != nil && len(ln) > 1 && (ln[0] == '+' || ln[0] == '-') {
+			if ln[0] == '+' {
+				err = p.Add(ln[1:])
+			} else {
+				err = p.Remove(ln[1:])
+			}
+
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			continue
+		}
+
 		start := time.Now()
-		results := spell.PartialMatch(d.trie, ln, 10, 10)
+		results := spell.Match(src, ln, 10, 10)
 		end := time.Since(start).Milliseconds()
 
 		table := tabby.New()
-		table.AddHeader("Rank", "Correction", "Weight", "Levenshtein Distance", "Insertions/Deletions", "Substitutions", "Transpositions", "Frequency", "Matching Characters", "Prefix", "Suffix", "Keyboard Distance")
+		table.AddHeader("Rank", "Correction", "Weight", "Levenshtein Distance", "Insertions/Deletions", "Substitutions", "Transpositions", "Frequency", "Matching Characters", "Prefix", "Suffix", "Keyboard Distance", "Edits")
 		for idx, res := range results {
 			metrics := res.Metrics()
-			table.AddLine(fmt.Sprintf("%v", idx+1), res.Word, res.Weight, metrics["levenshtein"], metrics["ins/del"], metrics["subs"], metrics["transpositions"], metrics["frequency"], "", metrics["prefix-length"], metrics["suffix-length"], "", metrics["keyboard-length"])
+			table.AddLine(fmt.Sprintf("%v", idx+1), res.Word, res.Weight, metrics["levenshtein"], metrics["ins/del"], metrics["subs"], metrics["transpositions"], metrics["frequency"], "", metrics["prefix-length"], metrics["suffix-length"], metrics["keyboard-length"], edits(res))
 		}
 
 		table.Print()
@@ -0,0 +1,266 @@
+package spell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	txt "github.com/hvlck/txt"
+)
+
+// A dictionary of words, stored in a trie.
+// Word frequencies are parsed when words are inserted, and stored in the `Data` of each word's terminal node along
+// with their normalized log-probability.
+type Dict struct {
+	*txt.Node
+	// Free-form information about the dictionary (source, language, license, ...), preserved in snapshots.
+	Meta map[string]string
+	// Model used to normalize word counts; the zero value uses DefaultFrequencyModel.
+	// Call Normalize after changing it.
+	Frequencies FrequencyModel
+	// Words that are never flagged, such as product names and code identifiers. Corrections for an ignored word are
+	// the word itself.
+	Ignore WordList
+	// Words that are never suggested, even though they are in the dictionary.
+	Block WordList
+	// Edit distance or similarity used to find and rank corrections; the zero value is OSA.
+	Metric Metric
+	// Costs of each edit under the Keyboard metric; nil uses DefaultCosts.
+	Costs *Costs
+	// Number of goroutines a search is split across, each searching whole subtrees of the root. 0 and 1 search on
+	// the calling goroutine, and negative values use one goroutine per CPU (runtime.GOMAXPROCS).
+	Parallelism int
+
+	// sum of all word counts
+	total float64
+	// number of words with a count
+	counted int
+	// incremented whenever a word or frequency changes
+	version uint64
+}
+
+// NewDict builds a ready-to-use dictionary from the embedded word list (`data/words.txt`).
+// Each call returns a new, independent trie. The list has no word counts, so every word gets the model's floor
+// log-probability and corrections are ranked by their edits alone; use Load with a frequency list to rank by
+// frequency.
+func NewDict() *Dict {
+	d := &Dict{Node: txt.NewTrie()}
+	if err := d.Load(bytes.NewReader(dict_file), Words); err != nil {
+		// the embedded list is fixed at compile time, so this can only be a programming error
+		panic(err)
+	}
+
+	return d
+}
+
+var (
+	ErrEmptyWord   = errors.New("empty word")
+	ErrInvalidWord = errors.New("word contains '*' or a line break")
+)
+
+// ids handed out to nodes created by Dict.Insert; 0 is reserved for the root
+var node_id uint32 = 1 << 31
+
+// Creates a new trie node.
+func new_node(rn rune) *txt.Node {
+	return &txt.Node{
+		Kids:      make(map[rune]*txt.Node),
+		Character: rn,
+		Id:        atomic.AddUint32(&node_id, 1),
+	}
+}
+
+// Reports whether `word` can be stored: `*` marks the end of a word in the trie, and line breaks would corrupt
+// line-based word lists.
+func valid_word(word string) bool {
+	return !strings.ContainsAny(word, "*\r\n")
+}
+
+// Insert adds `word` to the dictionary with its count in a corpus (`freq`, a decimal number such as `23135851162`),
+// or nil if the word has no count.
+// The trie layout is the same as txt.Node.Insert (one node per byte, terminated by a `*` node), but unlike
+// txt.Node.Insert, words containing punctuation or non-ASCII characters are stored whole instead of being cut off.
+// Inserting a word that is already present replaces its count.
+//
+// The word's log-probability is computed from the dictionary's current totals; call Normalize after inserting many
+// counted words to update every other word's.
+func (d *Dict) Insert(word string, freq []byte) error {
+	count, err := parse_count(freq)
+	if err != nil {
+		return err
+	}
+
+	return d.insert(word, count)
+}
+
+// Inserts `word` with a parsed count, NaN if it has none.
+func (d *Dict) insert(word string, count float64) error {
+	if len(word) == 0 {
+		return ErrEmptyWord
+	}
+
+	if !valid_word(word) {
+		return ErrInvalidWord
+	}
+
+	n := d.Node
+	for i := 0; i < len(word); i++ {
+		rn := rune(word[i])
+		next, ok := n.Kids[rn]
+		if !ok {
+			next = new_node(rn)
+			n.Kids[rn] = next
+		}
+		n = next
+	}
+
+	end, ok := n.Kids['*']
+	if !ok {
+		end = new_node('*')
+		end.Done = true
+		n.Kids['*'] = end
+	} else {
+		d.uncount(end)
+	}
+
+	if !math.IsNaN(count) {
+		d.total += count
+		d.counted++
+	}
+	end.Data = encode_freq(count, d.log_prob(count))
+	atomic.AddUint64(&d.version, 1)
+
+	return nil
+}
+
+// Removes the count of a word that is being replaced or removed from the totals.
+func (d *Dict) uncount(end *txt.Node) {
+	if count, _ := decode_freq(end.Data); !math.IsNaN(count) {
+		d.total -= count
+		d.counted--
+	}
+}
+
+// Returns the terminal node of `word`, or nil if the word is not in the dictionary.
+func (d *Dict) terminal(word string) *txt.Node {
+	if len(word) == 0 {
+		return nil
+	}
+
+	n := d.Node
+	for i := 0; i < len(word) && n != nil; i++ {
+		n = n.Kids[rune(word[i])]
+	}
+
+	if n == nil {
+		return nil
+	}
+
+	return n.Kids['*']
+}
+
+// Contains reports whether `word` is in the dictionary.
+func (d *Dict) Contains(word string) bool {
+	return d.terminal(word) != nil
+}
+
+// Walk calls `fn` for every word in the dictionary, in byte order, along with the word's count (NaN if it has none).
+// Walking stops early if `fn` returns false.
+func (d *Dict) Walk(fn func(word string, count float64) bool) {
+	walk(d.Node, nil, fn)
+}
+
+func walk(n *txt.Node, prefix []byte, fn func(word string, count float64) bool) bool {
+	keys := make([]rune, 0, len(n.Kids))
+	for rn := range n.Kids {
+		keys = append(keys, rn)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+
+	for _, rn := range keys {
+		v := n.Kids[rn]
+		if v.Done && len(v.Kids) == 0 {
+			count, _ := decode_freq(v.Data)
+			if len(prefix) > 0 && !fn(string(prefix), count) {
+				return false
+			}
+			continue
+		}
+
+		if !walk(v, append(prefix, byte(rn)), fn) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PartialMatch returns the `max` best corrections for `s` within `target` edit distances, in the same way as the
+// package-level PartialMatch, honoring the dictionary's ignore and block lists.
+func (d *Dict) PartialMatch(s string, target float64, max int) []Correction {
+	return Match(d, s, target, max)
+}
+
+// PartialMatchContext is PartialMatch, stopping the search once `ctx` is done. The best corrections found until then
+// are returned, and `partial` reports whether the search was stopped before it finished.
+func (d *Dict) PartialMatchContext(ctx context.Context, s string, target float64, max int) (c []Correction, partial bool) {
+	return MatchContext(ctx, d, s, target, max)
+}
+
+// Correct is the equivalent of the package-level Correct for the words in this dictionary, honoring its ignore and
+// block lists.
+func (d *Dict) Correct(word string, lim float64) map[string]float64 {
+	if d.Ignore.Match(word) {
+		return map[string]float64{word: 0}
+	}
+
+	return correct(d, word, lim)
+}
+
+// Version returns a number that changes whenever the results of a search could change: when words are inserted or
+// removed, frequencies are normalized, the ignore or block lists are edited, or the metric or its costs are changed.
+// Caches use it to discard stale results.
+func (d *Dict) Version() uint64 {
+	return metric_version(d.Metric, d.Costs, atomic.LoadUint64(&d.version), d.Ignore.version(), d.Block.version())
+}
+
+// Remove deletes `word` from the dictionary, reporting whether it was present.
+// Nodes that no longer lead to any word are pruned.
+func (d *Dict) Remove(word string) bool {
+	if len(word) == 0 {
+		return false
+	}
+
+	path := make([]*txt.Node, 0, len(word)+1)
+	n := d.Node
+	for i := 0; i < len(word) && n != nil; i++ {
+		path = append(path, n)
+		n = n.Kids[rune(word[i])]
+	}
+
+	if n == nil {
+		return false
+	}
+
+	end, ok := n.Kids['*']
+	if !ok {
+		return false
+	}
+	d.uncount(end)
+	delete(n.Kids, '*')
+	atomic.AddUint64(&d.version, 1)
+
+	// walk back up, removing nodes left without children
+	for i := len(word) - 1; i >= 0 && len(n.Kids) == 0; i-- {
+		delete(path[i].Kids, rune(word[i]))
+		n = path[i]
+	}
+
+	return true
+}
@@ -0,0 +1,411 @@
+package spell
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"unicode"
+)
+
+// Metric selects the distance used to compare words. The unweighted edit distances count insertions, deletions and
+// substitutions of a single character as one edit, and differ in how they treat transposed characters; Keyboard gives
+// each edit its own cost. The similarity metrics measure the characters two words share instead.
+type Metric uint8
+
+const (
+	// OSA is the optimal string alignment distance, which also counts swapping two adjacent characters as one edit
+	// (`liek` → `like`), as long as no character is edited again afterwards: `ca` → `abc` takes 3 edits. It is the
+	// default metric.
+	OSA Metric = iota
+	// Levenshtein is the plain Levenshtein distance, where swapping two adjacent characters takes two edits.
+	Levenshtein
+	// Damerau is the unrestricted Damerau-Levenshtein distance, which counts swaps as one edit even when characters
+	// are inserted between the swapped ones later: `ca` → `ac` → `abc` takes 2 edits. Unlike OSA, it satisfies the
+	// triangle inequality.
+	Damerau
+	// Keyboard is a weighted OSA distance for typos made on a QWERTY keyboard: substituting a character for one on a
+	// nearby key costs less than for one on a distant key, and insertions, deletions and transpositions each have
+	// their own cost, set by a Costs. Searches compare the total cost to their limit, so physically close typos are
+	// found at smaller limits.
+	Keyboard
+	// JaroWinkler is the Jaro-Winkler similarity, which rewards runes found near the same position in both words and
+	// a shared prefix. Like the other similarity metrics, its distance is 1 minus the similarity, so search limits are
+	// fractions such as 0.15, and searches visit far more of the dictionary than edit distance searches do.
+	JaroWinkler
+	// Dice is the Dice coefficient of the bigrams of both words, including one for each word's start and end: twice
+	// the number of shared bigrams, divided by the number of bigrams in both words.
+	Dice
+	// Jaccard is the Jaccard index of the bigrams of both words, counted like Dice: the number of shared bigrams,
+	// divided by the number of distinct bigrams of either word.
+	Jaccard
+)
+
+func (m Metric) String() string {
+	switch m {
+	case OSA:
+		return "osa"
+	case Levenshtein:
+		return "levenshtein"
+	case Damerau:
+		return "damerau"
+	case Keyboard:
+		return "keyboard"
+	case JaroWinkler:
+		return "jaro-winkler"
+	case Dice:
+		return "dice"
+	case Jaccard:
+		return "jaccard"
+	}
+
+	return "unknown"
+}
+
+// Distance returns the number of edits needed to turn `a` into `b` under the metric, using DefaultCosts for Keyboard,
+// or 1 minus their similarity under a similarity metric. Strings are compared rune by rune, and each byte of invalid
+// UTF-8 counts as one rune. The unweighted metrics are symmetric, and every edit distance is 0 only for equal strings.
+func (m Metric) Distance(a, b string) float64 {
+	return m.distance(nil, a, b)
+}
+
+// Distance under the metric, with the costs `c` for Keyboard (DefaultCosts if nil).
+func (m Metric) distance(c *Costs, a, b string) float64 {
+	r := new_lev_rows(b, m, c)
+	for i := 0; i < len(a); i++ {
+		r.push(i, a[i])
+	}
+
+	return r.distance(len(a))
+}
+
+// Costs of each edit under the Keyboard metric, turning an intended word into what was typed. Costs are in edits, so
+// a search within 2 edits finds every word with a total cost of at most 2; none may be negative.
+type Costs struct {
+	// Typing an extra character, leaving one out, and swapping two adjacent characters.
+	Insertion, Deletion, Transposition float64
+	// Cost of typing a character instead of the one on the next key, added again for each key further away, as
+	// measured by KeyProximity. Substitutions never cost more than MaxSubstitution, which is also the cost of
+	// substituting characters that are not on the keyboard.
+	Substitution, MaxSubstitution float64
+}
+
+// DefaultCosts makes typing a neighbouring key half an edit, and every other edit a whole one.
+var DefaultCosts = Costs{Insertion: 1, Deletion: 1, Transposition: 1, Substitution: .5, MaxSubstitution: 1}
+
+// KeyProximity between every pair of ASCII characters on the keyboard, or 0 if either is not on it.
+var key_distances = func() (d [128][128]uint8) {
+	on_keyboard := func(c byte) bool {
+		for _, k := range all_keys {
+			if k == unicode.ToLower(rune(c)) && k != ' ' {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for a := byte(0); a < 128; a++ {
+		for b := byte(0); b < 128; b++ {
+			if on_keyboard(a) && on_keyboard(b) {
+				d[a][b] = KeyProximity(rune(a), rune(b))
+			}
+		}
+	}
+
+	return d
+}()
+
+// Cost of typing `typed` instead of `intended`.
+func (c *Costs) substitution(intended, typed rune) float64 {
+	if intended == typed {
+		return 0
+	}
+
+	if intended < 0 || intended >= 128 || typed < 0 || typed >= 128 || key_distances[intended][typed] == 0 {
+		return c.MaxSubstitution
+	}
+
+	if v := c.Substitution * float64(key_distances[intended][typed]); v < c.MaxSubstitution {
+		return v
+	}
+
+	return c.MaxSubstitution
+}
+
+// Indices of the counts returned by operations, as stored in Correction.ld.
+const (
+	op_distance = iota
+	op_substitutions
+	op_indels
+	op_transpositions
+)
+
+// Costs of `m`, resolving nil to DefaultCosts for Keyboard; nil for the unweighted metrics.
+func (m Metric) costs(c *Costs) *Costs {
+	if m != Keyboard {
+		return nil
+	}
+
+	if c == nil {
+		return &DefaultCosts
+	}
+
+	return c
+}
+
+// Hashes the versions `v` of a source searched with metric `m` and costs `c`, so the result also changes whenever the
+// metric or the costs it uses do.
+func metric_version(m Metric, c *Costs, v ...uint64) uint64 {
+	v = append(v, uint64(m))
+	if c = m.costs(c); c != nil {
+		for _, f := range []float64{c.Insertion, c.Deletion, c.Transposition, c.Substitution, c.MaxSubstitution} {
+			v = append(v, math.Float64bits(f))
+		}
+	}
+
+	h := fnv.New64a()
+	b := make([]byte, 8)
+	for _, x := range v {
+		binary.LittleEndian.PutUint64(b, x)
+		h.Write(b)
+	}
+
+	return h.Sum64()
+}
+
+// Counts the edits of one of the shortest edit scripts between `x` and `y` under `m`, with the costs `c` for
+// Keyboard: the total distance, substitutions, insertions and deletions, and transpositions.
+func operations(m Metric, c *Costs, x, y string) [4]float64 {
+	var res [4]float64
+	if x == y {
+		return res
+	}
+
+	// similarity metrics count the edits of the default metric
+	if m.similarity() {
+		res = operations(OSA, nil, x, y)
+		res[op_distance] = 1 - similarity(m, []rune(x), []rune(y))
+		return res
+	}
+
+	res[op_distance] = align(m, c, []rune(x), []rune(y), func(e Edit) {
+		switch e.Op {
+		case Substitute:
+			res[op_substitutions]++
+		case Insert, Delete:
+			res[op_indels]++
+		case Transpose:
+			res[op_transpositions]++
+		}
+	})
+
+	return res
+}
+
+// Finds one of the shortest edit scripts turning `b` into `a` under `m`, with the costs `c` for Keyboard, and returns
+// its distance. Each edit is passed to `emit`, last first: positions decrease, except that the insertions and
+// deletions of an unrestricted Damerau transposition come before it. When several scripts are equally short, matches
+// and substitutions are preferred, then transpositions, and runes missing from `b` over runes missing from `a`.
+func align(m Metric, c *Costs, a, b []rune, emit func(e Edit)) float64 {
+	c = m.costs(c)
+	ins, del := 1.0, 1.0
+	if c != nil {
+		ins, del = c.Insertion, c.Deletion
+	}
+
+	// cost of substituting b[j-1] for a[i-1], and of swapping them with the characters before them
+	sub := func(i, j int) float64 {
+		switch {
+		case a[i-1] == b[j-1]:
+			return 0
+		case c != nil:
+			return c.substitution(a[i-1], b[j-1])
+		}
+
+		return 1
+	}
+	swap := func(i, j int) float64 {
+		if c != nil && a[i-1] != b[j-1] {
+			return c.Transposition
+		}
+
+		return sub(i, j)
+	}
+
+	// d[i][j] is the distance between a[:i] and b[:j]
+	d := make([][]float64, len(a)+1)
+	// for the unrestricted distance, the rows and columns just before the last occurrences of the characters that
+	// could be swapped at each cell; -1 if there are none
+	var tk, tl [][]int
+	if m == Damerau {
+		tk, tl = make([][]int, len(a)+1), make([][]int, len(a)+1)
+	}
+
+	for i := range d {
+		d[i] = make([]float64, len(b)+1)
+		if i > 0 {
+			d[i][0] = d[i-1][0] + del
+		}
+		if m == Damerau {
+			tk[i], tl[i] = make([]int, len(b)+1), make([]int, len(b)+1)
+		}
+	}
+
+	for j := 1; j <= len(b); j++ {
+		d[0][j] = d[0][j-1] + ins
+	}
+
+	// last row in which each rune of `a` was seen
+	da := map[rune]int{}
+	for i := 1; i <= len(a); i++ {
+		// last column in this row where b matched a[i-1]
+		db := 0
+		for j := 1; j <= len(b); j++ {
+			v := min(d[i-1][j-1]+sub(i, j), d[i-1][j]+del, d[i][j-1]+ins)
+			switch m {
+			case OSA, Keyboard:
+				if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+					v = min(v, d[i-2][j-2]+swap(i, j))
+				}
+			case Damerau:
+				k, l := da[b[j-1]], db
+				tk[i][j], tl[i][j] = k-1, l-1
+				if k > 0 && l > 0 {
+					v = min(v, d[k-1][l-1]+float64(i-k-1)+1+float64(j-l-1))
+				}
+
+				if a[i-1] == b[j-1] {
+					db = j
+				}
+			}
+
+			d[i][j] = v
+		}
+
+		da[a[i-1]] = i
+	}
+
+	for i, j := len(a), len(b); i > 0 || j > 0; {
+		if i > 0 && j > 0 && d[i][j] == d[i-1][j-1]+sub(i, j) {
+			if a[i-1] != b[j-1] {
+				emit(Edit{Op: Substitute, Pos: j - 1, Len: 1, From: b[j-1], To: a[i-1]})
+			}
+			i, j = i-1, j-1
+			continue
+		}
+
+		switch {
+		case (m == OSA || m == Keyboard) && i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && d[i][j] == d[i-2][j-2]+swap(i, j):
+			emit(Edit{Op: Transpose, Pos: j - 2, Len: 2, From: b[j-2], To: b[j-1]})
+			i, j = i-2, j-2
+			continue
+		case m == Damerau && i > 0 && j > 0:
+			k, l := tk[i][j], tl[i][j]
+			if k >= 0 && l >= 0 && d[i][j] == d[k][l]+float64(i-k-2)+1+float64(j-l-2) {
+				// the runes between the swapped ones are replaced: b[l+1:j-1] by a[k+1:i-1]
+				for n := i - 2; n > k; n-- {
+					emit(Edit{Op: Insert, Pos: j - 1, To: a[n]})
+				}
+				for n := j - 2; n > l; n-- {
+					emit(Edit{Op: Delete, Pos: n, Len: 1, From: b[n]})
+				}
+				emit(Edit{Op: Transpose, Pos: l, Len: j - l, From: b[l], To: b[j-1]})
+				i, j = k, l
+				continue
+			}
+		}
+
+		if i > 0 && (j == 0 || d[i][j] == d[i-1][j]+del) {
+			emit(Edit{Op: Insert, Pos: j, To: a[i-1]})
+			i--
+		} else {
+			emit(Edit{Op: Delete, Pos: j - 1, Len: 1, From: b[j-1]})
+			j--
+		}
+	}
+
+	return d[len(a)][len(b)]
+}
+
+// Op is the kind of an edit.
+type Op uint8
+
+const (
+	// Substitute replaces a rune with another.
+	Substitute Op = iota
+	// Insert adds a rune that is missing.
+	Insert
+	// Delete removes a rune.
+	Delete
+	// Transpose swaps two runes.
+	Transpose
+)
+
+func (o Op) String() string {
+	switch o {
+	case Substitute:
+		return "substitute"
+	case Insert:
+		return "insert"
+	case Delete:
+		return "delete"
+	case Transpose:
+		return "transpose"
+	}
+
+	return "unknown"
+}
+
+// Edit is one step of an edit script turning a misspelled word into its correction. Positions are in runes of the
+// misspelled word, and every edit refers to the word as it was typed, before any other edit is applied.
+type Edit struct {
+	Op Op
+	// First rune the edit applies to, and the number of runes it spans: one for substitutions and deletions, and two
+	// for transpositions, or more when an unrestricted Damerau transposition swaps runes with others between them,
+	// which are then replaced by separate deletions and insertions. Insertions span no runes, and add a rune before
+	// the one at Pos (or at the end of the word, if Pos is its length).
+	Pos, Len int
+	// For substitutions, the rune replaced and its replacement; deletions only have From, and insertions only have
+	// To. For transpositions, the first and last swapped runes as they were typed.
+	From, To rune
+}
+
+func (e Edit) String() string {
+	switch e.Op {
+	case Substitute:
+		return fmt.Sprintf("substitute %q with %q at %v", e.From, e.To, e.Pos)
+	case Insert:
+		return fmt.Sprintf("insert %q at %v", e.To, e.Pos)
+	case Delete:
+		return fmt.Sprintf("delete %q at %v", e.From, e.Pos)
+	case Transpose:
+		return fmt.Sprintf("transpose %q and %q at %v-%v", e.From, e.To, e.Pos, e.Pos+e.Len-1)
+	}
+
+	return "unknown"
+}
+
+// Edit script turning `y` into `x` under `m`, with the costs `c` for Keyboard, ordered by position.
+func edit_script(m Metric, c *Costs, x, y string) []Edit {
+	script := []Edit{}
+	if x == y {
+		return script
+	}
+
+	if m.similarity() {
+		m = OSA
+	}
+
+	align(m, c, []rune(x), []rune(y), func(e Edit) {
+		script = append(script, e)
+	})
+
+	// edits are found last first; a Damerau transposition and the edits between its runes are found in reverse
+	// order too, so reversing the whole script leaves the transposition first
+	for i, j := 0, len(script)-1; i < j; i, j = i+1, j-1 {
+		script[i], script[j] = script[j], script[i]
+	}
+
+	return script
+}
@@ -0,0 +1,481 @@
+package spell
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Textbook optimal string alignment distance; reference for OSA.
+func osa_reference(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(a)][len(b)]
+}
+
+// Unrestricted Damerau-Levenshtein distance, as given by Lowrance and Wagner; reference for Damerau.
+func damerau_reference(a, b string) int {
+	da := map[byte]int{}
+	inf := len(a) + len(b)
+
+	// d[i+1][j+1] is the distance between a[:i] and b[:j]
+	d := make([][]int, len(a)+2)
+	for i := range d {
+		d[i] = make([]int, len(b)+2)
+	}
+	d[0][0] = inf
+	for i := 0; i <= len(a); i++ {
+		d[i+1][0] = inf
+		d[i+1][1] = i
+	}
+	for j := 0; j <= len(b); j++ {
+		d[0][j+1] = inf
+		d[1][j+1] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		db := 0
+		for j := 1; j <= len(b); j++ {
+			k, l := da[b[j-1]], db
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+				db = j
+			}
+
+			d[i+1][j+1] = min(d[i][j]+cost, d[i+1][j]+1, d[i][j+1]+1, d[k][l]+(i-k-1)+1+(j-l-1))
+		}
+		da[a[i-1]] = i
+	}
+
+	return d[len(a)+1][len(b)+1]
+}
+
+func random_word(r *rand.Rand, alphabet string, max int) string {
+	runes := []rune(alphabet)
+	w := make([]rune, r.Intn(max+1))
+	for i := range w {
+		w[i] = runes[r.Intn(len(runes))]
+	}
+
+	return string(w)
+}
+
+func TestMetrics(t *testing.T) {
+	cases := []struct {
+		a, b string
+		lev  float64
+		osa  float64
+		dl   float64
+	}{
+		{"", "", 0, 0, 0},
+		{"", "abc", 3, 3, 3},
+		{"liek", "like", 2, 1, 1},
+		{"ca", "abc", 3, 3, 2},
+		{"abc", "ca", 3, 3, 2},
+		{"burn", "bayou", 4, 4, 4},
+		{"arrainged", "arraigned", 2, 1, 1},
+	}
+
+	for _, c := range cases {
+		got := []float64{Levenshtein.Distance(c.a, c.b), OSA.Distance(c.a, c.b), Damerau.Distance(c.a, c.b)}
+		if want := []float64{c.lev, c.osa, c.dl}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("%q -> %q: expected %v, got %v", c.a, c.b, want, got)
+		}
+	}
+
+	references := map[Metric]func(a, b string) int{Levenshtein: rt, OSA: osa_reference, Damerau: damerau_reference}
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 5000; n++ {
+		a, b := random_word(r, "abc", 7), random_word(r, "abc", 7)
+		for m, ref := range references {
+			want := float64(ref(a, b))
+			if got := m.Distance(a, b); got != want {
+				t.Fatalf("%v(%q, %q): expected %v, got %v", m, a, b, want, got)
+			}
+
+			ops := operations(m, nil, a, b)
+			if ops[op_distance] != want {
+				t.Fatalf("%v operations(%q, %q): expected distance %v, got %v", m, a, b, want, ops)
+			}
+
+			if sum := ops[op_substitutions] + ops[op_indels] + ops[op_transpositions]; sum != want {
+				t.Fatalf("%v operations(%q, %q): %v edits add up to %v", m, a, b, ops, sum)
+			}
+
+			if m == Levenshtein && ops[op_transpositions] != 0 {
+				t.Fatalf("levenshtein operations(%q, %q): unexpected transpositions in %v", a, b, ops)
+			}
+		}
+
+		// only the unrestricted distance is a metric in the strict sense
+		c := random_word(r, "abc", 7)
+		if Damerau.Distance(a, c) > Damerau.Distance(a, b)+Damerau.Distance(b, c) {
+			t.Fatalf("damerau: triangle inequality fails for %q, %q, %q", a, b, c)
+		}
+	}
+}
+
+func TestSearchMetrics(t *testing.T) {
+	words := []string{}
+	walk_terminals(d.Node, nil, func(word []byte, _ []byte) {
+		words = append(words, string(word))
+	})
+	sort.Strings(words)
+
+	for _, m := range []Metric{Levenshtein, OSA, Damerau, Keyboard} {
+		for _, s := range []string{"ca", "arrainged"} {
+			want := []string{}
+			for _, w := range words {
+				if m.Distance(w, s) <= 2 {
+					want = append(want, w)
+				}
+			}
+
+			c, _ := search_lev_context(context.Background(), d.Node, s, 2, m, nil)
+			got := []string{}
+			for _, v := range c {
+				got = append(got, v.Word)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("%v search for %q: expected %v corrections, got %v", m, s, len(want), len(got))
+			}
+		}
+	}
+
+	dict, _ := Load(strings.NewReader("abc\n"), Words)
+	if r := dict.PartialMatch("ca", 2, -1); len(r) != 0 {
+		t.Fatalf("expected nothing within 2 osa edits of ca, got %v", r)
+	}
+
+	dict.Metric = Damerau
+	if r := dict.PartialMatch("ca", 2, -1); len(r) != 1 || r[0].Word != "abc" {
+		t.Fatalf("expected abc within 2 damerau edits of ca, got %v", r)
+	}
+}
+
+func TestKeyboard(t *testing.T) {
+	cases := []struct {
+		a, b string
+		dist float64
+	}{
+		{"bad", "vad", .5},
+		{"tad", "vad", 1},
+		{"bad", "Bad", .5},
+		// shifted by an insertion, then a neighbouring key
+		{"bad", "xvad", 1.5},
+		{"bad", "abd", 1},
+		// a character that is not on the keyboard
+		{"café", "cafe", 1},
+		{"bad", "", 3},
+	}
+
+	for _, c := range cases {
+		if got := Keyboard.Distance(c.a, c.b); got != c.dist {
+			t.Fatalf("%q -> %q: expected %v, got %v", c.a, c.b, c.dist, got)
+		}
+	}
+
+	costs := []*Costs{nil, {Insertion: 2, Deletion: .5, Transposition: .25, Substitution: .3, MaxSubstitution: 3}}
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 5000; n++ {
+		a, b := random_word(r, "vbgtA", 6), random_word(r, "vbgtA", 6)
+		for _, c := range costs {
+			want := operations(Keyboard, c, a, b)
+			if got := Keyboard.distance(c, a, b); got != want[op_distance] {
+				t.Fatalf("%v keyboard(%q, %q): expected %v, got %v", c, a, b, want, got)
+			}
+		}
+
+		if Keyboard.Distance(a, b) > OSA.Distance(a, b) {
+			t.Fatalf("keyboard(%q, %q) costs more than osa", a, b)
+		}
+	}
+
+	dict, _ := Load(strings.NewReader("bad\ntad\n"), Words)
+	dict.Metric = Keyboard
+	if r := dict.PartialMatch("vad", .5, -1); len(r) != 1 || r[0].Word != "bad" {
+		t.Fatalf("expected only bad within half an edit of vad, got %v", r)
+	}
+
+	if r := dict.PartialMatch("vad", 1, -1); len(r) != 2 || r[0].Word != "bad" {
+		t.Fatalf("expected bad ranked above tad, got %v", r)
+	}
+
+	dict.Costs = &Costs{Insertion: 1, Deletion: 1, Transposition: 1, Substitution: 1, MaxSubstitution: 1}
+	if r := dict.PartialMatch("vad", .5, -1); len(r) != 0 {
+		t.Fatalf("expected no words within half an edit of vad with unit costs, got %v", r)
+	}
+
+	dict, _ = Load(strings.NewReader("ba\n"), Words)
+	dict.Metric = Keyboard
+	dict.Costs = &Costs{Insertion: 1, Deletion: 1, Transposition: .2, Substitution: .5, MaxSubstitution: 1}
+	if r := dict.Candidates("ab", .5); len(r) != 1 {
+		t.Fatalf("expected ba within a cheap transposition of ab, got %v", r)
+	}
+}
+
+func TestSearchCosts(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	words := []string{}
+	seen := map[string]bool{}
+	for len(words) < 2000 {
+		if w := random_word(rnd, "vbgtaé", 6); w != "" && !seen[w] {
+			seen[w] = true
+			words = append(words, w)
+		}
+	}
+	sort.Strings(words)
+
+	dict, _ := Load(strings.NewReader(strings.Join(words, "\n")), Words)
+	dict.Metric = Keyboard
+	costs := []*Costs{
+		{Insertion: 2, Deletion: .5, Transposition: .25, Substitution: .5, MaxSubstitution: 3},
+		{Insertion: .5, Deletion: 2, Transposition: .125, Substitution: .25, MaxSubstitution: 1},
+		{Insertion: 1, Deletion: 1, Transposition: 2, Substitution: 1, MaxSubstitution: 2},
+	}
+
+	for _, c := range costs {
+		dict.Costs = c
+		for n := 0; n < 20; n++ {
+			s, limit := random_word(rnd, "vbgtaé", 6), float64(rnd.Intn(5))/2
+			want := []string{}
+			for _, w := range words {
+				if Keyboard.distance(c, w, s) <= limit {
+					want = append(want, w)
+				}
+			}
+
+			got := []string{}
+			for _, v := range dict.Candidates(s, limit) {
+				got = append(got, v.Word)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("%v search for %q within %v: expected %v, got %v", *c, s, limit, want, got)
+			}
+		}
+	}
+}
+
+func TestRunes(t *testing.T) {
+	cases := []struct {
+		a, b string
+		lev  float64
+		osa  float64
+	}{
+		{"café", "cafe", 1, 1},
+		{"naïve", "naive", 1, 1},
+		{"naïve", "nïave", 2, 1},
+		{"crèche", "crêche", 1, 1},
+		{"привет", "привте", 2, 1},
+		{"кот", "кто", 2, 1},
+		{"мир", "мир", 0, 0},
+		{"мир", "world", 5, 5},
+		// invalid UTF-8 counts one rune per byte
+		{"caf\xe9", "café", 1, 1},
+		{strings.Repeat("а", 300), strings.Repeat("б", 300), 300, 300},
+		{strings.Repeat("ё", 400), "", 400, 400},
+	}
+
+	for _, c := range cases {
+		got := []float64{Levenshtein.Distance(c.a, c.b), OSA.Distance(c.a, c.b), operations(OSA, nil, c.a, c.b)[op_distance]}
+		if want := []float64{c.lev, c.osa, c.osa}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("%.20q -> %.20q: expected %v, got %v", c.a, c.b, want, got)
+		}
+	}
+
+	if ops := operations(OSA, nil, "привет", "привте"); ops != [4]float64{1, 0, 0, 1} {
+		t.Fatalf("expected one transposition, got %v", ops)
+	}
+
+	long := strings.Repeat("é", 1000)
+	lengths := []int{
+		PrefixLength("naïve", "naïf"),
+		PrefixLength("привет", "привычка"),
+		PrefixLength(long, long+"s"),
+		int(SharedCharacters("café", "cafe")),
+		int(SharedCharacters("тест", "текст")),
+		int(SharedCharacters(long, long)),
+	}
+	if want := []int{3, 4, 1000, 3, 2, 1000}; !reflect.DeepEqual(lengths, want) {
+		t.Fatalf("expected lengths %v, got %v", want, lengths)
+	}
+
+	c := Correction{Word: long + "s", ld: operations(OSA, nil, long+"s", long)}
+	c.weigh(long)
+	if c.prefix_len != 1000 || c.key_len != 1 {
+		t.Fatalf("expected a prefix of 1000 and a key length of 1, got %v and %v", c.prefix_len, c.key_len)
+	}
+
+	words := "naïve\nnaïf\ncafé\nпривет\nприветствие\n" + long + "\n"
+	dict, err := Load(strings.NewReader(words), Words)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	indexes := map[string]CandidateSource{"trie": dict, "symspell": must(NewSymSpell(dict, 2)), "linear": Linear(strings.Fields(words))}
+	queries := map[string]string{"naive": "naïve", "naif": "naïf", "caffé": "café", "првиет": "привет", long[2:] + "e": long}
+	for name, src := range indexes {
+		for q, want := range queries {
+			if r := Match(src, q, 2, 1); len(r) != 1 || r[0].Word != want {
+				t.Fatalf("%v: expected %.20q for %.20q, got %v", name, want, q, r)
+			}
+		}
+	}
+
+	if r := must(NewBKTree(dict)).Nearest("naif", 1); len(r) != 1 || r[0].Word != "naïf" {
+		t.Fatalf("bktree: expected naïf, got %v", r)
+	}
+
+	batch := dict.Batch([]string{"naïf", "naïv", "naïvr", "naive"}, 1, -1)
+	for q, r := range batch {
+		if want := dict.PartialMatch(q, 1, -1); !reflect.DeepEqual(r, want) {
+			t.Fatalf("batch: expected %v for %q, got %v", want, q, r)
+		}
+	}
+}
+
+// Applies an edit script to the word it was computed for.
+func apply_edits(typed string, script []Edit) string {
+	b := []rune(typed)
+	at := map[int]Edit{}
+	inserts := map[int][]rune{}
+	// second rune of each transposition, and the rune it is replaced by
+	swapped := map[int]rune{}
+	for _, e := range script {
+		switch e.Op {
+		case Insert:
+			inserts[e.Pos] = append(inserts[e.Pos], e.To)
+		case Transpose:
+			at[e.Pos] = e
+			swapped[e.Pos+e.Len-1] = b[e.Pos]
+		default:
+			at[e.Pos] = e
+		}
+	}
+
+	out := []rune{}
+	for p := 0; p <= len(b); p++ {
+		out = append(out, inserts[p]...)
+		if p == len(b) {
+			break
+		}
+
+		e, ok := at[p]
+		switch {
+		case ok && e.Op == Substitute:
+			out = append(out, e.To)
+		case ok && e.Op == Delete:
+		case ok && e.Op == Transpose:
+			out = append(out, b[p+e.Len-1])
+		default:
+			if r, ok := swapped[p]; ok {
+				out = append(out, r)
+			} else {
+				out = append(out, b[p])
+			}
+		}
+	}
+
+	return string(out)
+}
+
+func TestEdits(t *testing.T) {
+	dict, _ := Load(strings.NewReader("bad\nlike\nspelling\ncafé\nabc\n"), Words)
+	cases := []struct {
+		typed string
+		want  []Edit
+	}{
+		{"vad", []Edit{{Op: Substitute, Pos: 0, Len: 1, From: 'v', To: 'b'}}},
+		{"liek", []Edit{{Op: Transpose, Pos: 2, Len: 2, From: 'e', To: 'k'}}},
+		{"speling", []Edit{{Op: Insert, Pos: 3, To: 'l'}}},
+		{"cafée", []Edit{{Op: Delete, Pos: 4, Len: 1, From: 'e'}}},
+		{"caffe", []Edit{{Op: Delete, Pos: 2, Len: 1, From: 'f'}, {Op: Substitute, Pos: 4, Len: 1, From: 'e', To: 'é'}}},
+		{"bad", []Edit{}},
+	}
+
+	for _, c := range cases {
+		r := dict.PartialMatch(c.typed, 2, 1)
+		if len(r) != 1 {
+			t.Fatalf("expected a correction for %q, got %v", c.typed, r)
+		}
+
+		if got := r[0].Edits(); !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("%q -> %q: expected edits %v, got %v", c.typed, r[0].Word, c.want, got)
+		}
+	}
+
+	dict.Metric = Damerau
+	want := []Edit{{Op: Transpose, Pos: 0, Len: 2, From: 'c', To: 'a'}, {Op: Insert, Pos: 1, To: 'b'}}
+	found := false
+	for _, c := range dict.PartialMatch("ca", 2, 5) {
+		if c.Word != "abc" {
+			continue
+		}
+
+		found = true
+		if got := c.Edits(); !reflect.DeepEqual(got, want) || apply_edits("ca", got) != "abc" {
+			t.Fatalf("ca -> abc: expected edits %v, got %v", want, got)
+		}
+	}
+	if !found {
+		t.Fatalf("expected abc among the corrections for ca")
+	}
+
+	if e := (&Correction{Word: "bad"}).Edits(); len(e) != 0 {
+		t.Fatalf("expected no edits for an unweighed correction, got %v", e)
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	for n := 0; n < 5000; n++ {
+		a, b := random_word(rnd, "abcé", 7), random_word(rnd, "abcé", 7)
+		for _, m := range []Metric{Levenshtein, OSA, Damerau, Keyboard} {
+			script := edit_script(m, nil, a, b)
+			if got := apply_edits(b, script); got != a {
+				t.Fatalf("%v edits %v turn %q into %q, not %q", m, script, b, got, a)
+			}
+
+			counts := operations(m, nil, a, b)
+			counts[op_substitutions], counts[op_indels], counts[op_transpositions] = 0, 0, 0
+			for _, e := range script {
+				switch e.Op {
+				case Substitute:
+					counts[op_substitutions]++
+				case Insert, Delete:
+					counts[op_indels]++
+				case Transpose:
+					counts[op_transpositions]++
+				}
+			}
+			if ops := operations(m, nil, a, b); ops != counts {
+				t.Fatalf("%v edits %v of %q and %q do not match %v", m, script, b, a, ops)
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package spell
+
+import (
+	"math"
+	"path"
+	"regexp"
+	"sync"
+)
+
+// WordList is a set of words and patterns, used for a dictionary's ignore and block lists.
+// Entries are exact words, glob patterns (path.Match syntax, e.g. `*_id` or `v[0-9]*`) or regular expressions
+// (regexp syntax), which must match the whole word. The zero value is an empty list, and a WordList is safe for
+// concurrent use.
+type WordList struct {
+	mu      sync.RWMutex
+	exact   map[string]bool
+	globs   []string
+	regexps []*regexp.Regexp
+	// number of changes made to the list
+	changes uint64
+}
+
+// Add adds exact words to the list.
+func (l *WordList) Add(words ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.exact == nil {
+		l.exact = map[string]bool{}
+	}
+
+	for _, w := range words {
+		l.exact[w] = true
+	}
+	l.changes++
+}
+
+// AddGlob adds a glob pattern to the list.
+func (l *WordList) AddGlob(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.globs = append(l.globs, pattern)
+	l.changes++
+	return nil
+}
+
+// AddRegexp adds a regular expression to the list. The expression is anchored, so it must match whole words.
+func (l *WordList) AddRegexp(expr string) error {
+	re, err := regexp.Compile(`^(?:` + expr + `)$`)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.regexps = append(l.regexps, re)
+	l.changes++
+	return nil
+}
+
+// Remove removes an exact word, glob pattern or regular expression from the list, reporting whether it was present.
+func (l *WordList) Remove(entry string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	removed := l.exact[entry]
+	delete(l.exact, entry)
+
+	globs := l.globs[:0]
+	for _, v := range l.globs {
+		if v == entry {
+			removed = true
+		} else {
+			globs = append(globs, v)
+		}
+	}
+	l.globs = globs
+
+	anchored := `^(?:` + entry + `)$`
+	regexps := l.regexps[:0]
+	for _, v := range l.regexps {
+		if v.String() == anchored {
+			removed = true
+		} else {
+			regexps = append(regexps, v)
+		}
+	}
+	l.regexps = regexps
+
+	if removed {
+		l.changes++
+	}
+
+	return removed
+}
+
+// Number of changes made to the list, for Version methods.
+func (l *WordList) version() uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.changes
+}
+
+// Match reports whether `word` is in the list, either exactly or by matching a pattern.
+func (l *WordList) Match(word string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.exact[word] {
+		return true
+	}
+
+	for _, v := range l.globs {
+		// patterns are checked when added
+		if ok, _ := path.Match(v, word); ok {
+			return true
+		}
+	}
+
+	for _, v := range l.regexps {
+		if v.MatchString(word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Removes corrections whose words are in the list.
+func (l *WordList) filter(c []Correction) []Correction {
+	kept := c[:0]
+	for _, v := range c {
+		if !l.Match(v.Word) {
+			kept = append(kept, v)
+		}
+	}
+
+	return kept
+}
+
+// Result for a word on an ignore list: the word itself, as an exact match.
+func ignored(s string) []Correction {
+	return []Correction{{Word: s, Weight: math.Inf(1)}}
+}
@@ -0,0 +1,88 @@
+package spell
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWordList(t *testing.T) {
+	l := WordList{}
+	if l.Match("anything") {
+		t.Fatal("expected empty list to match nothing")
+	}
+
+	l.Add("kubectl")
+	if err := l.AddGlob("*_id"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.AddRegexp(`v[0-9]+`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []string{"kubectl", "user_id", "v12"} {
+		if !l.Match(v) {
+			t.Fatalf("expected %q to match", v)
+		}
+	}
+
+	for _, v := range []string{"kubect", "user_ids", "v12a", "xv12"} {
+		if l.Match(v) {
+			t.Fatalf("expected %q not to match", v)
+		}
+	}
+
+	if err := l.AddGlob("[a-"); err == nil {
+		t.Fatal("expected malformed glob to be rejected")
+	}
+
+	if err := l.AddRegexp("("); err == nil {
+		t.Fatal("expected malformed regexp to be rejected")
+	}
+
+	if !l.Remove("*_id") || !l.Remove(`v[0-9]+`) || !l.Remove("kubectl") || l.Remove("kubectl") {
+		t.Fatal("expected each entry to be removed once")
+	}
+
+	if l.Match("user_id") || l.Match("v12") || l.Match("kubectl") {
+		t.Fatal("expected removed entries not to match")
+	}
+}
+
+func TestDictLists(t *testing.T) {
+	d, _ := Load(strings.NewReader("duck\nduct\ndock\nfetch\n"), Words)
+	d.Block.Add("duct")
+	d.Ignore.AddGlob("*_id")
+
+	for _, v := range d.PartialMatch("duxk", 1, 5) {
+		if v.Word == "duct" {
+			t.Fatal("blocked word suggested by PartialMatch")
+		}
+	}
+
+	if _, ok := d.Correct("duxt", 1)["duct"]; ok {
+		t.Fatal("blocked word suggested by Correct")
+	}
+
+	r := d.PartialMatch("user_id", 2, 5)
+	if len(r) != 1 || r[0].Word != "user_id" || !math.IsInf(r[0].Weight, 1) {
+		t.Fatalf("expected ignored word to be an exact match, got %v", r)
+	}
+
+	if c := d.Correct("user_id", 2); len(c) != 1 || c["user_id"] != 0 {
+		t.Fatalf("expected ignored word to be correct, got %v", c)
+	}
+
+	l := NewLayers(Layer{Name: "base", Source: d})
+	l.Block.Add("dock")
+	for _, v := range l.Candidates("duck", 1) {
+		if v.Word == "duct" || v.Word == "dock" {
+			t.Fatalf("blocked word %q suggested by Layers", v.Word)
+		}
+	}
+
+	if r := l.PartialMatch("order_id", 1, 5); len(r) != 1 || r[0].Word != "order_id" {
+		t.Fatalf("expected layer's ignore list to be honored, got %v", r)
+	}
+}
@@ -0,0 +1,129 @@
+package spell
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"sync/atomic"
+
+	txt "github.com/hvlck/txt"
+)
+
+// FrequencyModel turns the raw word counts of a dictionary into smoothed log-probabilities, so that ranking does not
+// depend on the scale of the counts in the source file.
+//
+// A word counted `c` times has the log-probability log((c + Smoothing) / (N + Smoothing × V)), where N is the sum of
+// all counts and V the number of counted words. Words without a count, such as those loaded from plain word lists or
+// added to a personal dictionary, have the log-probability `Floor`.
+type FrequencyModel struct {
+	// Additive smoothing applied to every count.
+	Smoothing float64
+	// Log-probability of words without a count.
+	Floor float64
+}
+
+// Frequency model used by dictionaries that do not set their own, and for tries built without a Dict.
+// Words without a count are treated as occurring once in a billion words, below nearly every counted word in a
+// large corpus.
+var DefaultFrequencyModel = FrequencyModel{Smoothing: 1, Floor: math.Log(1e-9)}
+
+// Frequency data of a word, stored in its terminal node: the raw count and its log-probability, as float64 bits.
+// The count is NaN if the word has none.
+const freq_data_len = 16
+
+func encode_freq(count, logp float64) []byte {
+	b := make([]byte, freq_data_len)
+	binary.LittleEndian.PutUint64(b, math.Float64bits(count))
+	binary.LittleEndian.PutUint64(b[8:], math.Float64bits(logp))
+
+	return b
+}
+
+// Decodes the count (NaN if there is none) and log-probability stored in a terminal node. Data that was not written
+// by a Dict, e.g. from txt.Node.Insert, has no count and the default floor.
+func decode_freq(b []byte) (float64, float64) {
+	if len(b) != freq_data_len {
+		return math.NaN(), DefaultFrequencyModel.Floor
+	}
+
+	return math.Float64frombits(binary.LittleEndian.Uint64(b)), math.Float64frombits(binary.LittleEndian.Uint64(b[8:]))
+}
+
+// Parses a textual count, as found in dictionary files. Empty counts are NaN.
+func parse_count(b []byte) (float64, error) {
+	if len(b) == 0 {
+		return math.NaN(), nil
+	}
+
+	f, err := strconv.ParseFloat(string(b), 64)
+	if err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, ErrBadFrequency
+	}
+
+	return f, nil
+}
+
+// Returns the frequency model of the dictionary.
+func (d *Dict) model() FrequencyModel {
+	if d.Frequencies == (FrequencyModel{}) {
+		return DefaultFrequencyModel
+	}
+
+	return d.Frequencies
+}
+
+// Log-probability of a word counted `count` times under the current totals.
+func (d *Dict) log_prob(count float64) float64 {
+	m := d.model()
+	if math.IsNaN(count) {
+		return m.Floor
+	}
+
+	return math.Log((count + m.Smoothing) / (d.total + m.Smoothing*float64(d.counted)))
+}
+
+// Normalize recomputes the log-probability of every word from the dictionary's counts and frequency model.
+// The loaders call it once a source has been read; it only needs to be called directly after changing
+// Dict.Frequencies, or after inserting many counted words with Insert, which only normalizes the word inserted.
+func (d *Dict) Normalize() {
+	d.total = 0
+	d.counted = 0
+	each_terminal(d.Node, func(n *txt.Node) {
+		if count, _ := decode_freq(n.Data); !math.IsNaN(count) {
+			d.total += count
+			d.counted++
+		}
+	})
+
+	each_terminal(d.Node, func(n *txt.Node) {
+		count, _ := decode_freq(n.Data)
+		n.Data = encode_freq(count, d.log_prob(count))
+	})
+	atomic.AddUint64(&d.version, 1)
+}
+
+// Calls `fn` for the terminal node of every word in the trie, in no particular order.
+func each_terminal(n *txt.Node, fn func(n *txt.Node)) {
+	for _, v := range n.Kids {
+		if v.Done && len(v.Kids) == 0 {
+			if n.Id != 0 {
+				fn(v)
+			}
+			continue
+		}
+
+		each_terminal(v, fn)
+	}
+}
+
+// Frequency returns the count of `word` in the dictionary's source (NaN if it has none) and its normalized
+// log-probability. `ok` is false if the word is not in the dictionary.
+func (d *Dict) Frequency(word string) (count float64, logp float64, ok bool) {
+	n := d.terminal(word)
+	if n == nil {
+		return 0, 0, false
+	}
+
+	count, logp = decode_freq(n.Data)
+	return count, logp, true
+}
@@ -0,0 +1,78 @@
+package spell
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFrequencyModel(t *testing.T) {
+	d, err := Load(strings.NewReader("the,7\nof,2\nzebra,0\n"), CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Insert("kubectl", nil)
+
+	// N = 9, V = 3, smoothing of 1
+	want := map[string]float64{
+		"the":     math.Log(8.0 / 12),
+		"of":      math.Log(3.0 / 12),
+		"zebra":   math.Log(1.0 / 12),
+		"kubectl": DefaultFrequencyModel.Floor,
+	}
+
+	for w, logp := range want {
+		if _, got, _ := d.Frequency(w); math.Abs(got-logp) > 1e-12 {
+			t.Fatalf("expected log-probability %v for %q, got %v", logp, w, got)
+		}
+	}
+
+	d.Frequencies = FrequencyModel{Smoothing: 0, Floor: -50}
+	d.Normalize()
+	if _, got, _ := d.Frequency("the"); math.Abs(got-math.Log(7.0/9)) > 1e-12 {
+		t.Fatalf("expected unsmoothed log-probability, got %v", got)
+	}
+
+	if _, got, _ := d.Frequency("kubectl"); got != -50 {
+		t.Fatalf("expected configured floor, got %v", got)
+	}
+
+	// replacing and removing counted words keeps the totals up to date
+	d.Insert("the", []byte("1"))
+	d.Remove("of")
+	d.Insert("a", []byte("2"))
+	if _, got, _ := d.Frequency("a"); math.Abs(got-math.Log(2.0/3)) > 1e-12 {
+		t.Fatalf("expected log-probability from updated totals, got %v", got)
+	}
+}
+
+func TestFrequencyScale(t *testing.T) {
+	// the same relative frequencies at different scales rank identically
+	small, _ := Load(strings.NewReader("bat,1\ncat,10\nhat,100\n"), CSV)
+	large, _ := Load(strings.NewReader("bat,1000000\ncat,10000000\nhat,100000000\n"), CSV)
+	small.Frequencies = FrequencyModel{Floor: DefaultFrequencyModel.Floor}
+	large.Frequencies = small.Frequencies
+	small.Normalize()
+	large.Normalize()
+
+	for _, w := range []string{"bat", "cat", "hat"} {
+		_, a, _ := small.Frequency(w)
+		_, b, _ := large.Frequency(w)
+		if math.Abs(a-b) > 1e-12 {
+			t.Fatalf("expected equal log-probabilities for %q, got %v and %v", w, a, b)
+		}
+	}
+
+	weights := map[string]float64{}
+	for _, c := range small.Candidates("zat", 1) {
+		c.weigh("zat")
+		weights[c.Word] = c.Weight
+	}
+
+	for _, c := range large.Candidates("zat", 1) {
+		c.weigh("zat")
+		if math.Abs(weights[c.Word]-c.Weight) > 1e-9 {
+			t.Fatalf("expected equal weights for %q, got %v and %v", c.Word, weights[c.Word], c.Weight)
+		}
+	}
+}
@@ -5,6 +5,6 @@ go 1.18
 require github.com/hvlck/txt v0.0.0-20220808013555-61d867c9885b
 
 require (
-	github.com/cheynewallace/tabby v1.1.1 // indirect
+	github.com/cheynewallace/tabby v1.1.1
 	golang.org/x/text v0.13.0
 )
@@ -0,0 +1,540 @@
+package spell
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	txt "github.com/hvlck/txt"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+var (
+	ErrBadAffix    = errors.New("malformed affix rule")
+	ErrBadFlag     = errors.New("malformed flag")
+	ErrBadEncoding = errors.New("unsupported encoding")
+)
+
+// LoadHunspell imports a Hunspell dictionary, given its word list (`.dic`) and affix file (`.aff`).
+// Every stem is expanded with its prefix and suffix rules, so inflected forms are inserted into the trie as ordinary
+// words and can be suggested by PartialMatch.
+//
+// The supported subset of the affix format is:
+// SET, FLAG (single character, long, num and UTF-8), AF aliases, PFX and SFX rules with conditions and continuation
+// flags (applied one level deep), cross products of prefixes and suffixes, NEEDAFFIX and FORBIDDENWORD.
+// Other directives, such as compounding and suggestion options, are ignored.
+func (d *Dict) LoadHunspell(dic, aff io.Reader) error {
+	a, err := parse_aff(aff)
+	if err != nil {
+		return fmt.Errorf("affix file: %w", err)
+	}
+
+	err = a.expand(d, dic)
+	d.Normalize()
+	if err != nil {
+		return fmt.Errorf("dictionary file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadHunspell builds a new dictionary from a Hunspell word list and affix file.
+func LoadHunspell(dic, aff io.Reader) (*Dict, error) {
+	d := &Dict{Node: txt.NewTrie()}
+	if err := d.LoadHunspell(dic, aff); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Parsed Hunspell affix file.
+type affixes struct {
+	enc encoding.Encoding
+	// FLAG type: "" (single character), "long", "num" or "UTF-8"
+	flag_type string
+	// flag aliases, 1-indexed in the file
+	aliases [][]string
+	// affix rules by flag
+	rules map[string]*affix_group
+
+	need_affix string
+	forbidden  string
+}
+
+// All rules sharing a single PFX or SFX flag.
+type affix_group struct {
+	prefix bool
+	// whether the rules can be combined with affixes of the other kind
+	cross bool
+	rules []affix_rule
+}
+
+// A single PFX or SFX rule.
+type affix_rule struct {
+	// characters removed from the stem before adding
+	strip string
+	// characters added to the stem
+	add string
+	// continuation flags, applied to the affixed word
+	cont []string
+	cond condition
+}
+
+// Hunspell affix condition, a simplified regular expression made up of characters, `.` and bracketed character
+// classes.
+type condition []char_class
+
+type char_class struct {
+	any    bool
+	negate bool
+	chars  []rune
+}
+
+func (c char_class) match(r rune) bool {
+	if c.any {
+		return true
+	}
+
+	for _, v := range c.chars {
+		if v == r {
+			return !c.negate
+		}
+	}
+
+	return c.negate
+}
+
+func parse_condition(s string) (condition, error) {
+	if s == "." {
+		return nil, nil
+	}
+
+	c := condition{}
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		switch rs[i] {
+		case '.':
+			c = append(c, char_class{any: true})
+		case '[':
+			cl := char_class{}
+			i++
+			if i < len(rs) && rs[i] == '^' {
+				cl.negate = true
+				i++
+			}
+
+			for ; i < len(rs) && rs[i] != ']'; i++ {
+				cl.chars = append(cl.chars, rs[i])
+			}
+
+			if i == len(rs) {
+				return nil, ErrBadAffix
+			}
+			c = append(c, cl)
+		default:
+			c = append(c, char_class{chars: []rune{rs[i]}})
+		}
+	}
+
+	return c, nil
+}
+
+// Reports whether the condition matches the beginning (prefix) or end (suffix) of `w`.
+func (c condition) matches(w []rune, prefix bool) bool {
+	if len(c) > len(w) {
+		return false
+	}
+
+	offset := 0
+	if !prefix {
+		offset = len(w) - len(c)
+	}
+
+	for i, cl := range c {
+		if !cl.match(w[offset+i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Applies the rule to `word`, returning the affixed word and whether the rule applies.
+func (r affix_rule) apply(word string, prefix bool) (string, bool) {
+	if !r.cond.matches([]rune(word), prefix) {
+		return "", false
+	}
+
+	if prefix {
+		if !strings.HasPrefix(word, r.strip) {
+			return "", false
+		}
+		word = r.add + word[len(r.strip):]
+	} else {
+		if !strings.HasSuffix(word, r.strip) {
+			return "", false
+		}
+		word = word[:len(word)-len(r.strip)] + r.add
+	}
+
+	return word, len(word) > 0
+}
+
+// Finds a Hunspell encoding by the name used in SET.
+func lookup_encoding(name string) (encoding.Encoding, error) {
+	upper := strings.ToUpper(name)
+	switch {
+	case upper == "UTF-8":
+		return unicode.UTF8, nil
+	case strings.HasPrefix(upper, "ISO8859-"):
+		name = "ISO-8859-" + upper[len("ISO8859-"):]
+	case strings.HasPrefix(upper, "MICROSOFT-CP"):
+		name = "windows-" + upper[len("MICROSOFT-CP"):]
+	}
+
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err != nil || enc == nil {
+		return nil, ErrBadEncoding
+	}
+
+	return enc, nil
+}
+
+// Splits a flag field into individual flags, resolving AF aliases.
+func (a *affixes) flags(s string) ([]string, error) {
+	if len(a.aliases) > 0 {
+		if n, err := strconv.Atoi(s); err == nil {
+			if n < 1 || n > len(a.aliases) {
+				return nil, ErrBadFlag
+			}
+			return a.aliases[n-1], nil
+		}
+	}
+
+	return a.split_flags(s)
+}
+
+func (a *affixes) split_flags(s string) ([]string, error) {
+	flags := []string{}
+	switch a.flag_type {
+	case "long":
+		rs := []rune(s)
+		if len(rs)%2 != 0 {
+			return nil, ErrBadFlag
+		}
+
+		for i := 0; i < len(rs); i += 2 {
+			flags = append(flags, string(rs[i:i+2]))
+		}
+	case "num":
+		for _, v := range strings.Split(s, ",") {
+			if _, err := strconv.Atoi(v); err != nil {
+				return nil, ErrBadFlag
+			}
+			flags = append(flags, v)
+		}
+	default:
+		for _, v := range s {
+			flags = append(flags, string(v))
+		}
+	}
+
+	return flags, nil
+}
+
+// Reads an entire Hunspell file, decoding it from `enc`.
+func read_lines(r io.Reader, enc encoding.Encoding) ([]string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+	if enc != nil {
+		if b, err = enc.NewDecoder().Bytes(b); err != nil {
+			return nil, err
+		}
+	}
+
+	lines := []string{}
+	scn := bufio.NewScanner(bytes.NewReader(b))
+	scn.Buffer(nil, len(b)+1)
+	for scn.Scan() {
+		lines = append(lines, strings.TrimRight(scn.Text(), "\r"))
+	}
+
+	return lines, scn.Err()
+}
+
+func parse_aff(r io.Reader) (*affixes, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &affixes{rules: map[string]*affix_group{}}
+
+	// the encoding has to be known before anything else can be decoded; SET itself is always ASCII
+	raw, _ := read_lines(bytes.NewReader(b), nil)
+	for i, ln := range raw {
+		if f := strings.Fields(ln); len(f) == 2 && f[0] == "SET" {
+			if a.enc, err = lookup_encoding(f[1]); err != nil {
+				return nil, &ParseError{Line: i + 1, Text: ln, Err: err}
+			}
+		}
+	}
+
+	lines, err := read_lines(bytes.NewReader(b), a.enc)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, ln := range lines {
+		if err := a.parse_line(ln); err != nil {
+			return nil, &ParseError{Line: i + 1, Text: ln, Err: err}
+		}
+	}
+
+	return a, nil
+}
+
+// Parses a single line of an affix file.
+func (a *affixes) parse_line(ln string) error {
+	f := strings.Fields(ln)
+	if len(f) == 0 || strings.HasPrefix(f[0], "#") {
+		return nil
+	}
+
+	switch f[0] {
+	case "FLAG":
+		if len(f) < 2 {
+			return ErrBadFlag
+		}
+		a.flag_type = f[1]
+	case "AF":
+		// the first AF line is the number of aliases
+		if len(f) < 2 {
+			return ErrBadFlag
+		}
+
+		if _, err := strconv.Atoi(f[1]); err == nil && a.aliases == nil {
+			a.aliases = [][]string{}
+			return nil
+		}
+
+		flags, err := a.split_flags(f[1])
+		if err != nil {
+			return err
+		}
+		a.aliases = append(a.aliases, flags)
+	case "NEEDAFFIX", "PSEUDOROOT":
+		if len(f) > 1 {
+			a.need_affix = f[1]
+		}
+	case "FORBIDDENWORD":
+		if len(f) > 1 {
+			a.forbidden = f[1]
+		}
+	case "PFX", "SFX":
+		return a.parse_affix(f)
+	}
+
+	return nil
+}
+
+// Parses a PFX/SFX header (`SFX flag cross count`) or rule (`SFX flag strip add[/flags] condition`).
+func (a *affixes) parse_affix(f []string) error {
+	if len(f) < 4 {
+		return ErrBadAffix
+	}
+
+	prefix := f[0] == "PFX"
+	g, ok := a.rules[f[1]]
+	if !ok {
+		// header
+		if _, err := strconv.Atoi(f[3]); err != nil || (f[2] != "Y" && f[2] != "N") {
+			return ErrBadAffix
+		}
+
+		a.rules[f[1]] = &affix_group{prefix: prefix, cross: f[2] == "Y"}
+		return nil
+	}
+
+	if g.prefix != prefix {
+		return ErrBadAffix
+	}
+
+	rule := affix_rule{}
+	if f[2] != "0" {
+		rule.strip = f[2]
+	}
+
+	add := f[3]
+	if idx := strings.IndexRune(add, '/'); idx != -1 {
+		cont, err := a.flags(add[idx+1:])
+		if err != nil {
+			return err
+		}
+		rule.cont = cont
+		add = add[:idx]
+	}
+
+	if add != "0" {
+		rule.add = add
+	}
+
+	cond := "."
+	if len(f) > 4 {
+		cond = f[4]
+	}
+
+	c, err := parse_condition(cond)
+	if err != nil {
+		return err
+	}
+	rule.cond = c
+
+	g.rules = append(g.rules, rule)
+	return nil
+}
+
+// Reads a `.dic` file, inserting every stem and its affixed forms into `d`.
+func (a *affixes) expand(d *Dict, r io.Reader) error {
+	lines, err := read_lines(r, a.enc)
+	if err != nil {
+		return err
+	}
+
+	for i, ln := range lines {
+		// the first line is the approximate word count
+		if i == 0 {
+			if _, err := strconv.Atoi(strings.TrimSpace(ln)); err == nil {
+				continue
+			}
+		}
+
+		// morphological fields follow the word after whitespace
+		ln = strings.TrimSpace(ln)
+		if idx := strings.IndexAny(ln, " \t"); idx != -1 {
+			ln = ln[:idx]
+		}
+
+		if len(ln) == 0 {
+			continue
+		}
+
+		word, flags, err := a.parse_entry(ln)
+		if err == nil {
+			err = a.insert(d, word, flags)
+		}
+
+		if err != nil {
+			return &ParseError{Line: i + 1, Text: lines[i], Err: err}
+		}
+	}
+
+	return nil
+}
+
+// Splits a `.dic` entry into its word and flags. Slashes in words are escaped with `\/`.
+func (a *affixes) parse_entry(ln string) (string, []string, error) {
+	for i := 0; i < len(ln); i++ {
+		if ln[i] == '/' && (i == 0 || ln[i-1] != '\\') {
+			flags, err := a.flags(ln[i+1:])
+			return strings.ReplaceAll(ln[:i], "\\/", "/"), flags, err
+		}
+	}
+
+	return strings.ReplaceAll(ln, "\\/", "/"), nil, nil
+}
+
+func has_flag(flags []string, f string) bool {
+	if len(f) == 0 {
+		return false
+	}
+
+	for _, v := range flags {
+		if v == f {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Inserts `word` and all of the words generated by its affix flags.
+func (a *affixes) insert(d *Dict, word string, flags []string) error {
+	if has_flag(flags, a.forbidden) {
+		return nil
+	}
+
+	if !has_flag(flags, a.need_affix) {
+		if err := d.Insert(word, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range flags {
+		g, ok := a.rules[f]
+		if !ok {
+			continue
+		}
+
+		for _, rule := range g.rules {
+			w, ok := rule.apply(word, g.prefix)
+			if !ok {
+				continue
+			}
+
+			if !has_flag(rule.cont, a.need_affix) {
+				if err := d.Insert(w, nil); err != nil {
+					return err
+				}
+			}
+
+			// continuation flags of the rule are applied to the affixed word
+			for _, c := range rule.cont {
+				if next, ok := a.rules[c]; ok && next != g {
+					if err := next.insert(d, w); err != nil {
+						return err
+					}
+				}
+			}
+
+			// cross products with affixes of the other kind on the stem
+			if !g.cross {
+				continue
+			}
+
+			for _, c := range flags {
+				if next, ok := a.rules[c]; ok && next.cross && next.prefix != g.prefix {
+					if err := next.insert(d, w); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Inserts every word generated by applying the group's rules to `word`.
+func (g *affix_group) insert(d *Dict, word string) error {
+	for _, rule := range g.rules {
+		if w, ok := rule.apply(word, g.prefix); ok {
+			if err := d.Insert(w, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package spell
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const test_aff = `SET UTF-8
+TRY esianrtolcdugmphbyfvkwzESIANRTOLCDUGMPHBYFVKWZ'
+NEEDAFFIX X
+FORBIDDENWORD !
+
+PFX A Y 1
+PFX A   0     re         .
+
+SFX D Y 4
+SFX D   0     d          e
+SFX D   y     ied        [^aeiou]y
+SFX D   0     ed         [^ey]
+SFX D   0     ed         [aeiou]y
+
+SFX S Y 2
+SFX S   y     ies        [^aeiou]y
+SFX S   0     s          [^y]
+
+SFX G Y 2
+SFX G   e     ing/S      e
+SFX G   0     ing/S      [^e]
+`
+
+const test_dic = `6
+create/ADG
+try/DS
+work/AG
+walk/X
+ked/S
+colour/! po:noun
+`
+
+func TestLoadHunspell(t *testing.T) {
+	d, err := LoadHunspell(strings.NewReader(test_dic), strings.NewReader(test_aff))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	present := []string{
+		"create", "created", "creating", "creatings", "recreate", "recreated", "recreating",
+		"try", "tried", "tries",
+		"work", "working", "rework", "reworking",
+		"keds",
+	}
+	for _, v := range present {
+		if !d.Contains(v) {
+			t.Fatalf("expected %q to be in dictionary", v)
+		}
+	}
+
+	// stem needs an affix, forbidden word, rules whose conditions do not match
+	absent := []string{"walk", "colour", "trys", "createed", "worked"}
+	for _, v := range absent {
+		if d.Contains(v) {
+			t.Fatalf("expected %q not to be in dictionary", v)
+		}
+	}
+
+	r := PartialMatch(d.Node, "recreatd", 1, 5)
+	found := false
+	for _, v := range r {
+		if v.Word == "recreated" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Fatalf("expected inflected form to be suggested, got %v", r)
+	}
+}
+
+func TestHunspellFlags(t *testing.T) {
+	aff := "FLAG long\nSFX Aa Y 1\nSFX Aa 0 s .\nAF 1\nAF Aa\n"
+	d, err := LoadHunspell(strings.NewReader("2\ncat/Aa\ndog/1\n"), strings.NewReader(aff))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []string{"cat", "cats", "dog", "dogs"} {
+		if !d.Contains(v) {
+			t.Fatalf("expected %q to be in dictionary", v)
+		}
+	}
+
+	aff = "SET ISO8859-1\nFLAG num\nSFX 101 Y 1\nSFX 101 0 s .\n"
+	d, err = LoadHunspell(strings.NewReader("1\ncaf\xe9/101\n"), strings.NewReader(aff))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !d.Contains("cafés") {
+		t.Fatal("expected ISO8859-1 entries to be decoded")
+	}
+}
+
+func TestHunspellErrors(t *testing.T) {
+	_, err := LoadHunspell(strings.NewReader("1\nfoo/A\n"), strings.NewReader("SFX A Y 1\nSFX A 0 s [a\n"))
+
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Line != 2 || !errors.Is(err, ErrBadAffix) {
+		t.Fatalf("expected malformed condition on line 2, got %v", err)
+	}
+
+	_, err = LoadHunspell(strings.NewReader("1\nfoo/ABC\n"), strings.NewReader("FLAG long\n"))
+	if !errors.As(err, &perr) || perr.Line != 2 || !errors.Is(err, ErrBadFlag) {
+		t.Fatalf("expected malformed flag on line 2, got %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package spell
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// A single dictionary in a stack of Layers.
+type Layer struct {
+	// Name of the layer, reported in Correction.Layer.
+	Name string
+	// Dictionary searched for this layer: a *Dict, *MappedDict, *SymSpell, *BKTree, another *Layers, or any other
+	// CandidateSource.
+	Source CandidateSource
+	// When several layers contain the same word, the correction from the layer with the highest priority is kept.
+	// Layers with equal priority are ordered by when they were added.
+	Priority int
+	// Added to the log-probability of every word found in this layer, so words from e.g. a domain glossary are
+	// ranked above general words at the same distance. A boost of math.Log(10) ranks words as if they were ten times
+	// as frequent.
+	Boost float64
+}
+
+// Layers combines several dictionaries, such as a general word list, a product glossary and a user's personal words,
+// into one searchable dictionary. Layers is safe for concurrent use.
+//
+// Words ignored or blocked by any layer's dictionary are ignored or blocked by the stack, in addition to those on
+// the stack's own lists.
+type Layers struct {
+	// Words that are never flagged.
+	Ignore WordList
+	// Words that are never suggested.
+	Block WordList
+
+	mu     sync.RWMutex
+	layers []Layer
+	// number of layers added or removed
+	changes uint64
+}
+
+// NewLayers stacks the given layers.
+func NewLayers(layers ...Layer) *Layers {
+	l := &Layers{}
+	for _, v := range layers {
+		l.Add(v)
+	}
+
+	return l
+}
+
+// Add adds a layer to the stack.
+func (l *Layers) Add(layer Layer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.layers = append(l.layers, layer)
+	l.changes++
+	sort.SliceStable(l.layers, func(i, j int) bool {
+		return l.layers[i].Priority > l.layers[j].Priority
+	})
+}
+
+// Remove removes every layer called `name`, reporting whether any were removed.
+func (l *Layers) Remove(name string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	kept := l.layers[:0]
+	for _, v := range l.layers {
+		if v.Name != name {
+			kept = append(kept, v)
+		}
+	}
+
+	removed := len(kept) != len(l.layers)
+	l.layers = kept
+	if removed {
+		l.changes++
+	}
+
+	return removed
+}
+
+// Sources whose results can change, such as dictionaries and layers.
+type versioned interface {
+	Version() uint64
+}
+
+// Version returns a number that changes whenever the results of a search could change: when layers are added or
+// removed, the lists are edited, or the version of a layer's source changes.
+func (l *Layers) Version() uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	// removing a layer can lower the sum of the versions, so they are hashed instead
+	h := fnv.New64a()
+	b := make([]byte, 8)
+	for _, v := range []uint64{l.changes, l.Ignore.version(), l.Block.version()} {
+		binary.LittleEndian.PutUint64(b, v)
+		h.Write(b)
+	}
+
+	for _, layer := range l.layers {
+		if s, ok := layer.Source.(versioned); ok {
+			binary.LittleEndian.PutUint64(b, s.Version())
+			h.Write(b)
+		}
+	}
+
+	return h.Sum64()
+}
+
+// Candidates searches every layer, keeping only the highest priority correction for each word.
+func (l *Layers) Candidates(s string, limit float64) []Correction {
+	c, _ := l.candidates_context(context.Background(), s, limit)
+	return c
+}
+
+// Searches layers in order until `ctx` is done; layers after the one that was stopped are skipped.
+func (l *Layers) candidates_context(ctx context.Context, s string, limit float64) ([]Correction, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	seen := map[string]bool{}
+	res := []Correction{}
+	// layers are sorted by priority, so the first layer to produce a word wins
+	for _, layer := range l.layers {
+		found, partial := candidates(ctx, layer.Source, s, limit)
+		for _, c := range found {
+			if seen[c.Word] || l.blocked(c.Word) {
+				continue
+			}
+			seen[c.Word] = true
+
+			c.LogProb += layer.Boost
+			// nested layers report their innermost layer
+			if len(c.Layer) == 0 {
+				c.Layer = layer.Name
+			}
+			res = append(res, c)
+		}
+
+		if partial {
+			return res, true
+		}
+	}
+
+	return res, false
+}
+
+// PartialMatch returns the `max` best corrections for `s` within `target` edit distances across all layers, in the
+// same way as the package-level PartialMatch.
+func (l *Layers) PartialMatch(s string, target float64, max int) []Correction {
+	return Match(l, s, target, max)
+}
+
+func (l *Layers) ignores(s string) bool {
+	if l.Ignore.Match(s) {
+		return true
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, layer := range l.layers {
+		if i, ok := layer.Source.(ignorer); ok && i.ignores(s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *Layers) blocks(s string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.blocked(s)
+}
+
+// Reports whether `s` is on the stack's block list or any layer's, with the lock held.
+func (l *Layers) blocked(s string) bool {
+	if l.Block.Match(s) {
+		return true
+	}
+
+	for _, layer := range l.layers {
+		if b, ok := layer.Source.(blocker); ok && b.blocks(s) {
+			return true
+		}
+	}
+
+	return false
+}
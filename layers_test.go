@@ -0,0 +1,101 @@
+package spell
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLayers(t *testing.T) {
+	base, err := Load(strings.NewReader("kubernetes,1\ncontainer,50\ncontainers,20\n"), CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domain, err := Load(strings.NewReader("kubectl\ncontainerd\ncontainer\n"), Words)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.Buffer{}
+	if _, err := domain.WriteMapped(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	mapped, err := NewMappedDict(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	personal, _ := Load(strings.NewReader("kubectx\n"), Words)
+
+	l := NewLayers(
+		Layer{Name: "base", Source: base},
+		Layer{Name: "domain", Source: mapped, Priority: 1, Boost: 100},
+	)
+	l.Add(Layer{Name: "personal", Source: NewLayers(Layer{Name: "user", Source: personal}), Priority: 2})
+
+	r := l.Candidates("containr", 2)
+	layers := map[string]string{}
+	for _, v := range r {
+		if _, ok := layers[v.Word]; ok {
+			t.Fatalf("duplicate correction %v", v.Word)
+		}
+		layers[v.Word] = v.Layer
+
+		if v.Word == "container" && v.LogProb != DefaultFrequencyModel.Floor+100 {
+			t.Fatalf("expected domain boost to be applied, got log-probability %v", v.LogProb)
+		}
+	}
+
+	want := map[string]string{"container": "domain", "containerd": "domain", "containers": "base"}
+	for w, layer := range want {
+		if layers[w] != layer {
+			t.Fatalf("expected %v from %q, got %q", w, layer, layers[w])
+		}
+	}
+
+	r = l.PartialMatch("kubectz", 1, 3)
+	found := map[string]string{}
+	for _, v := range r {
+		found[v.Word] = v.Layer
+	}
+
+	if found["kubectx"] != "user" || found["kubectl"] != "domain" {
+		t.Fatalf("expected nested and mapped layers to be searched, got %v", r)
+	}
+
+	if !l.Remove("domain") || l.Remove("domain") {
+		t.Fatal("expected domain layer to be removed once")
+	}
+
+	for _, v := range l.Candidates("containr", 2) {
+		if v.Layer == "domain" {
+			t.Fatalf("removed layer still searched: %v", v)
+		}
+	}
+}
+
+func TestLayersBlock(t *testing.T) {
+	base, _ := Load(strings.NewReader("damn\ndam\n"), Words)
+	base.Block.Add("damn")
+	extra, _ := Load(strings.NewReader("damn\n"), Words)
+
+	l := NewLayers(
+		Layer{Name: "base", Source: base, Priority: 1},
+		Layer{Name: "extra", Source: NewLayers(Layer{Name: "nested", Source: extra})},
+	)
+	for _, v := range l.PartialMatch("damm", 1, 5) {
+		if v.Word == "damn" {
+			t.Fatalf("expected a word blocked by the base layer never to be suggested, got %v", v)
+		}
+	}
+
+	base.Block.Remove("damn")
+	extra.Block.Add("damn")
+	for _, v := range l.PartialMatch("damm", 1, 5) {
+		if v.Word == "damn" {
+			t.Fatalf("expected a word blocked by a nested layer never to be suggested, got %v", v)
+		}
+	}
+}
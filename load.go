@@ -0,0 +1,138 @@
+package spell
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	txt "github.com/hvlck/txt"
+)
+
+// A Loader reads dictionary entries from `r` and inserts them into `d`.
+type Loader interface {
+	Load(d *Dict, r io.Reader) error
+}
+
+// LoaderFunc adapts an ordinary function to the Loader interface.
+type LoaderFunc func(d *Dict, r io.Reader) error
+
+func (f LoaderFunc) Load(d *Dict, r io.Reader) error {
+	return f(d, r)
+}
+
+var (
+	// Plain word list, one word per line (e.g. `data/words.txt`). Words are stored without frequency data.
+	Words Loader = delimited{}
+	// `word,frequency` lines.
+	CSV Loader = delimited{sep: ',', freq: true}
+	// `word<tab>frequency` lines.
+	TSV Loader = delimited{sep: '\t', freq: true}
+	// The `unigram_freq.csv` layout from the Kaggle English Word Frequency dataset: a `word,count` header followed by
+	// `word,count` lines.
+	Unigram Loader = delimited{sep: ',', freq: true, header: "word,count"}
+)
+
+var (
+	ErrMissingFrequency = errors.New("missing frequency column")
+	ErrExtraColumns     = errors.New("too many columns")
+	ErrBadFrequency     = errors.New("frequency is not a number")
+)
+
+// ParseError describes a malformed line in a dictionary source.
+type ParseError struct {
+	// 1-indexed line number
+	Line int
+	// Contents of the offending line
+	Text string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("spell: line %d: %v: %q", e.Line, e.Err, e.Text)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Load reads all entries from `r` into the dictionary using the loader `l`.
+// Entries are added to any words already present.
+func (d *Dict) Load(r io.Reader, l Loader) error {
+	err := l.Load(d, r)
+	d.Normalize()
+
+	return err
+}
+
+// Load builds a new dictionary from `r` using the loader `l`.
+func Load(r io.Reader, l Loader) (*Dict, error) {
+	d := &Dict{Node: txt.NewTrie()}
+	if err := d.Load(r, l); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Line-oriented loader for word lists and delimited word/frequency files.
+type delimited struct {
+	// column separator
+	sep byte
+	// whether a frequency column is required
+	freq bool
+	// optional header line, skipped if it is the first line
+	header string
+}
+
+func (f delimited) Load(d *Dict, r io.Reader) error {
+	scn := bufio.NewScanner(r)
+	line := 0
+	for scn.Scan() {
+		line++
+		ln := bytes.TrimRight(scn.Bytes(), "\r")
+		if len(bytes.TrimSpace(ln)) == 0 {
+			continue
+		}
+
+		if line == 1 && len(f.header) > 0 && string(bytes.ToLower(ln)) == f.header {
+			continue
+		}
+
+		word, count, err := f.parse(ln)
+		if err == nil {
+			err = d.insert(word, count)
+		}
+
+		if err != nil {
+			return &ParseError{Line: line, Text: string(ln), Err: err}
+		}
+	}
+
+	return scn.Err()
+}
+
+// Splits a single line into its word and count (NaN if the format has none).
+func (f delimited) parse(ln []byte) (string, float64, error) {
+	if !f.freq {
+		return string(bytes.TrimSpace(ln)), math.NaN(), nil
+	}
+
+	r := bytes.Split(ln, []byte{f.sep})
+	switch {
+	case len(r) < 2:
+		return "", 0, ErrMissingFrequency
+	case len(r) > 2:
+		return "", 0, ErrExtraColumns
+	}
+
+	freq := bytes.TrimSpace(r[1])
+	if len(freq) == 0 {
+		return "", 0, ErrMissingFrequency
+	}
+
+	count, err := parse_count(freq)
+	return string(bytes.TrimSpace(r[0])), count, err
+}
@@ -0,0 +1,72 @@
+package spell
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	cases := []struct {
+		name   string
+		loader Loader
+		input  string
+		// word -> count, NaN for none
+		want map[string]float64
+	}{
+		{"words", Words, "apple\nbanana\r\n\ncherry\n", map[string]float64{"apple": math.NaN(), "banana": math.NaN(), "cherry": math.NaN()}},
+		{"csv", CSV, "apple,10\nbanana, 2.5\n", map[string]float64{"apple": 10, "banana": 2.5}},
+		{"tsv", TSV, "apple\t10\nbanana\t3\n", map[string]float64{"apple": 10, "banana": 3}},
+		{"unigram", Unigram, "word,count\nthe,23135851162\nof,13151942776\n", map[string]float64{"the": 23135851162, "of": 13151942776}},
+		{"punctuation", Words, "don't\ncafé\n", map[string]float64{"don't": math.NaN(), "café": math.NaN()}},
+	}
+
+	for _, c := range cases {
+		d, err := Load(strings.NewReader(c.input), c.loader)
+		if err != nil {
+			t.Fatalf("%v: %v", c.name, err)
+		}
+
+		for w, freq := range c.want {
+			count, _, ok := d.Frequency(w)
+			if !ok {
+				t.Fatalf("%v: expected %q to be in dictionary", c.name, w)
+			}
+
+			if count != freq && !(math.IsNaN(count) && math.IsNaN(freq)) {
+				t.Fatalf("%v: expected frequency %v for %q, got %v", c.name, freq, w, count)
+			}
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := []struct {
+		loader Loader
+		input  string
+		line   int
+		err    error
+	}{
+		{CSV, "apple,10\nbanana\n", 2, ErrMissingFrequency},
+		{CSV, "apple,10,3\n", 1, ErrExtraColumns},
+		{TSV, "apple\tlots\n", 1, ErrBadFrequency},
+		{CSV, "apple,-3\n", 1, ErrBadFrequency},
+		{CSV, "apple,\n", 1, ErrMissingFrequency},
+		{Unigram, "word,count\nthe,1\n,2\n", 3, ErrEmptyWord},
+		{Words, "a*b\n", 1, ErrInvalidWord},
+	}
+
+	for _, c := range cases {
+		_, err := Load(strings.NewReader(c.input), c.loader)
+
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Fatalf("expected ParseError for %q, got %v", c.input, err)
+		}
+
+		if perr.Line != c.line || !errors.Is(err, c.err) {
+			t.Fatalf("expected %v on line %v, got %v", c.err, c.line, err)
+		}
+	}
+}
@@ -2,20 +2,32 @@ package spell
 
 import (
 	"bytes"
+	"container/heap"
+	"context"
+	_ "embed"
 	"math"
 	"sort"
-	"strconv"
 	"unicode"
+	"unicode/utf8"
 
 	txt "github.com/hvlck/txt"
 )
 
-// go:embed ./data/words.txt
+//go:embed data/words.txt
 var dict_file []byte
 
-// Loads the dictionary words list
-func loadDict() [][]byte {
-	return bytes.Split(dict_file, []byte("\n"))
+// Loads the dictionary words list, skipping blank lines
+func loadDict() Linear {
+	lines := bytes.Split(dict_file, []byte("\n"))
+	words := make(Linear, 0, len(lines))
+	for _, v := range lines {
+		v = bytes.TrimSpace(v)
+		if len(v) > 0 {
+			words = append(words, string(v))
+		}
+	}
+
+	return words
 }
 
 var dict = loadDict()
@@ -30,26 +42,11 @@ var dict = loadDict()
 // `tad` and `bad` are both options, but the "b" in `bad` is closer physically on the keyboard than the "t" in
 // `tab`, and so would be the better choice
 func Correct(word string, lim float64) map[string]float64 {
-	// all found matches
-	matches := map[string]float64{}
-
-	for i := 0; i < len(dict); i++ {
-		// levenshtein distance of correction
-		l := levenshtein(word, string(dict[i]))
-		if l <= lim {
-			matches[string(dict[i])] = l
-			lim = l
-		}
-	}
-
-	return matches
-}
-
-type Dict struct {
-	*txt.Node
+	return correct(dict, word, lim)
 }
 
-// A word correction. A copy of the original word is not stored.
+// A word correction. A copy of the original word is not stored, though weighing a correction keeps a reference to it
+// for Edits.
 type Correction struct {
 	// Corrected word
 	Word string
@@ -58,76 +55,82 @@ type Correction struct {
 	// Number of characters that both words share at the beginning.
 	// For example, grace and grant have a prefix_len of 3 as they both share `gra` at the beginning.
 	// Higher is better.
-	prefix_len uint8
-	suffix_len uint8
-	// Frequency of use of the word in an English text corpus
-	frequency float64
+	prefix_len int
+	suffix_len int
+	// Number of times the word was counted in the dictionary's source corpus, 0 if unknown.
+	Frequency float64
+	// Smoothed log-probability of the word, normalized by the dictionary's FrequencyModel. Higher is more common.
+	LogProb float64
 	// Sum of the distance between each character in the original and corrected word. Lower is better.
-	key_len uint8
+	key_len int
 	// Weight of word correction. Higher values mean the correction is closer to the original word.
 	Weight float64
+	// Name of the layer the word was found in, when searching Layers.
+	Layer string
+
+	// word the correction was weighed for, and the metric and costs it was found with
+	original string
+	weighed  bool
+	metric   Metric
+	costs    *Costs
 }
 
-func (c *Correction) Metrics() map[string]float64 {
-	return map[string]float64{
-		"levenshtein":     c.ld[0],
-		"ins/del":         c.ld[1],
-		"subs":            c.ld[2],
-		"transpositions":  c.ld[3],
-		"frequency":       c.frequency,
-		"prefix-length":   float64(c.prefix_len),
-		"suffix-length":   float64(c.suffix_len),
-		"keyboard-length": float64(c.key_len),
+// Creates an unweighted correction for `word`, with the frequency data stored in its terminal node.
+func new_correction(word string, lev [4]float64, data []byte) Correction {
+	count, logp := decode_freq(data)
+	if math.IsNaN(count) {
+		count = 0
 	}
+
+	return Correction{ld: lev, Word: word, Weight: 0, Frequency: count, LogProb: logp}
 }
 
-// Searches for all words in the trie within a fixed `limit` edit distance away from the original string `s`.
-func search_lev(n *txt.Node, s, b string, limit float64, prev ...Correction) []Correction {
-	if n == nil {
-		return make([]Correction, 0)
+// Edits returns the edits turning the word the correction was found for into the correction, ordered by position, as
+// counted by Metrics. Exact matches, and corrections that were not weighed (such as candidates straight from a
+// CandidateSource), have no edits.
+func (c *Correction) Edits() []Edit {
+	if !c.weighed {
+		return []Edit{}
 	}
 
-	if n.Id == 0 {
-		for rn, v := range n.Kids {
-			prev = append(prev, search_lev(v, s, string(rn), limit)...)
-		}
-		return prev
-	} else {
-		for rn, v := range n.Kids {
-			lev := levenshtein_with_operations(b, s)
-
-			if v.Done && len(v.Kids) == 0 {
-				if lev[0] <= limit {
-					freq, err := strconv.ParseFloat(string(v.Data), 64)
-					if err != nil {
-						freq = 0
-					}
-					prev = append(prev, Correction{ld: lev, Word: b, Weight: 0, frequency: freq})
-				}
-
-				continue
-			} else {
-				prev = append(prev, search_lev(v, s, b+string(rn), limit)...)
-			}
-		}
+	return edit_script(c.metric, c.costs, c.Word, c.original)
+}
+
+// Metrics returns the edits and features of a correction, and its similarity to the word it was found for under
+// each similarity metric, which is 0 for corrections that were not weighed.
+func (c *Correction) Metrics() map[string]float64 {
+	word, original := []rune(c.Word), []rune(c.original)
+	if !c.weighed {
+		original = nil
 	}
 
-	return prev
+	return map[string]float64{
+		"jaro-winkler":    jaro_winkler(word, original),
+		"dice":            ngram_similarity(Dice, word, original),
+		"jaccard":         ngram_similarity(Jaccard, word, original),
+		"levenshtein":     c.ld[op_distance],
+		"ins/del":         c.ld[op_indels],
+		"subs":            c.ld[op_substitutions],
+		"transpositions":  c.ld[op_transpositions],
+		"frequency":       c.Frequency,
+		"log-probability": c.LogProb,
+		"prefix-length":   float64(c.prefix_len),
+		"suffix-length":   float64(c.suffix_len),
+		"keyboard-length": float64(c.key_len),
+	}
 }
 
-// PrefixLength calculates the number of same characters at the beginning of both strings.
-func PrefixLength(o, t string) uint8 {
-	var n uint8 = 0
-	for i, v := range t {
-		if len(o)-1 < i {
-			return n
-		}
-
-		if v == rune(o[i]) {
-			n++
-		} else {
+// PrefixLength calculates the number of same characters (runes) at the beginning of both strings.
+func PrefixLength(o, t string) int {
+	n := 0
+	for _, v := range t {
+		r, size := utf8.DecodeRuneInString(o)
+		if size == 0 || r != v {
 			break
 		}
+
+		n++
+		o = o[size:]
 	}
 
 	return n
@@ -140,8 +143,15 @@ var keys = [][]rune{
 	{'z', 'x', 'c', 'v', 'b', 'n', 'm', ',', '.', '/', ' ', ' ', ' '},
 }
 
-// one-dimensional array of all keys
-var all_keys = make([]rune, 0, 13*4)
+// one-dimensional array of all keys, built once so concurrent searches never write to it
+var all_keys = func() []rune {
+	all := make([]rune, 0, 13*4)
+	for _, v := range keys {
+		all = append(all, v...)
+	}
+
+	return all
+}()
 
 // Returns the absolute value.
 func abs[T int | int8 | uint8](x T) T {
@@ -172,12 +182,6 @@ func KeyProximity(original, target rune) uint8 {
 		return 0
 	}
 
-	if len(all_keys) == 0 {
-		for _, v := range keys {
-			all_keys = append(all_keys, v...)
-		}
-	}
-
 	// row
 	rO := 0
 	// column
@@ -223,7 +227,7 @@ const (
 	KEYDIST_WEIGHT   = 20
 	PREFIX_WEIGHT    = 1
 	SUFFIX_WEIGHT    = PREFIX_WEIGHT
-	FREQUENCY_WEIGHT = 10
+	FREQUENCY_WEIGHT = 1
 	MATCHES_WEIGHT   = 1
 )
 
@@ -242,12 +246,16 @@ var lev_weights = map[int]float64{
 func SharedCharacters(original, target string) float64 {
 	matches := 0.0
 
-	length := min(len(original), len(target))
+	for _, v := range original {
+		r, size := utf8.DecodeRuneInString(target)
+		if size == 0 {
+			break
+		}
 
-	for i := 0; i < length; i++ {
-		if original[i] == target[i] {
+		if v == r {
 			matches += 1
 		}
+		target = target[size:]
 	}
 
 	return matches
@@ -255,16 +263,18 @@ func SharedCharacters(original, target string) float64 {
 
 // reverses a string
 func reverse(s string) string {
-	res := ""
-	for _, v := range s {
-		res = string(v) + res
+	res := []rune(s)
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
 	}
-	return res
+	return string(res)
 }
 
 // Weighs a given correction for the provided original string.
 // todo: improvements to waiting algorithm, documentation
 func (c *Correction) weigh(original string) {
+	c.original, c.weighed = original, true
+
 	// todo: sometimes this returns true for multiple values, and occassionally doesn't work at all
 	if c.Word == original {
 		c.Weight = math.Inf(1)
@@ -272,20 +282,13 @@ func (c *Correction) weigh(original string) {
 	}
 
 	// sum of key lengths
-	var key_len uint8 = 0
-	for i, v := range c.Word {
-		if len(original)-1 < i {
-			break
-		}
-
-		key_len += KeyProximity(v, rune(original[i]))
+	word, runes := []rune(c.Word), []rune(original)
+	key_len := 0
+	for i := 0; i < len(word) && i < len(runes); i++ {
+		key_len += int(KeyProximity(word[i], runes[i]))
 	}
 
-	if len(original) > len(c.Word) {
-		key_len += uint8(len(original) - len(c.Word))
-	} else if len(c.Word) > len(original) {
-		key_len += uint8(len(c.Word) - len(original))
-	}
+	key_len += abs(len(runes) - len(word))
 
 	magic_weight := 0.0
 	c.key_len = key_len
@@ -293,9 +296,13 @@ func (c *Correction) weigh(original string) {
 
 	c.suffix_len = PrefixLength(reverse(c.Word), reverse(original))
 
+	// the kinds of edits are counted as they always were, whatever the metric, so only the distance depends on it
+	ld := levenshtein_with_operations(c.Word, original)
+	ld[op_distance] = c.ld[op_distance]
+
 	var wld_div float64 = 1
-	for i := 0; i < len(c.ld); i++ {
-		w := c.ld[i] * lev_weights[i]
+	for i := 0; i < len(ld); i++ {
+		w := ld[i] * lev_weights[i]
 		if w != 0 {
 			wld_div *= w
 		}
@@ -314,168 +321,117 @@ func (c *Correction) weigh(original string) {
 		magic_weight += 25
 	}
 
-	var wfrequency float64 = FREQUENCY_WEIGHT * c.frequency
+	var wfrequency float64 = FREQUENCY_WEIGHT * c.LogProb
 	var wmatches float64 = MATCHES_WEIGHT * SharedCharacters(original, c.Word)
 
 	c.Weight = wld + wkey_len + wprefix_len + wfrequency + wmatches + wsuffix_len + magic_weight
 }
 
-// Returns all matches in the given trie within `target` edit distances of `s`. Max is the maximum number of corrections
-// to return. Exact matches will have a weight of +Inf.
-// todo: -1 value for `max` to include all matches
+// Returns the best matches in the given trie within `target` edit distances of `s`, best first: the first correction
+// has the highest weight, and corrections with equal weights are ordered by edit distance, then by word. Max is the
+// maximum number of corrections to return, or -1 to return every match; fewer are returned if there are fewer
+// matches. Exact matches will have a weight of +Inf.
 func PartialMatch(n *txt.Node, s string, target float64, max int) []Correction {
-	f := search_lev(n, s, "", target)
-
-	var lim float64 = 0
-	res := make([]Correction, max)
+	return Match(trie{n}, s, target, max)
+}
 
-	last := 0
-	for _, v := range f {
-		v.weigh(s)
+// PartialMatchContext is PartialMatch, stopping the search once `ctx` is done. The best corrections found until then
+// are returned, and `partial` reports whether the search was stopped before it finished.
+func PartialMatchContext(ctx context.Context, n *txt.Node, s string, target float64, max int) (c []Correction, partial bool) {
+	return MatchContext(ctx, trie{n}, s, target, max)
+}
 
-		// first element
-		if lim == 0 {
-			lim = v.Weight
-		}
+// Reports whether `c` ranks above `o`: it has a higher weight, or an equal weight and a smaller edit distance, or
+// both and a word earlier in byte order, so rankings never depend on the order candidates were found in.
+func (c *Correction) better(o *Correction) bool {
+	if c.Weight != o.Weight {
+		return c.Weight > o.Weight
+	}
 
-		if v.Weight >= lim {
-			// res is filled
-			if n := res[last]; len(n.Word) != 0 && n.ld[0] != 0 {
-				// search for element with lowest weight, replace it
-				for i, k := range res {
-					// levenshtein and weight of word being examined is less than word currently in final results
-					if v.Weight > k.Weight && v.ld[0] <= target {
-						res[i] = v
-						sort.Slice(res, func(i, j int) bool {
-							return res[i].Weight < res[j].Weight
-						})
-						break
-					}
-				}
-
-			} else if last < max {
-				res[last] = v
-				if last+1 < max {
-					last++
-				}
-				sort.Slice(res, func(i, j int) bool {
-					return res[i].Weight < res[j].Weight
-				})
-			}
-		}
-		// ignore words with weights smaller than limit
+	if c.ld[0] != o.ld[0] {
+		return c.ld[0] < o.ld[0]
 	}
 
-	return res
+	return c.Word < o.Word
 }
 
-// returns the minimum of a set of numbers
-func min[T int | uint8 | float64](v ...T) T {
-	m := v[0]
+// Min-heap of the best corrections found so far, worst first.
+type ranking []Correction
+
+func (h ranking) Len() int            { return len(h) }
+func (h ranking) Less(i, j int) bool  { return h[j].better(&h[i]) }
+func (h ranking) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *ranking) Push(x interface{}) { *h = append(*h, x.(Correction)) }
+func (h *ranking) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
 
-	for _, k := range v {
-		if k < m {
-			m = k
-		}
+// Adds a weighed correction to the ranking, keeping at most `max` corrections (all of them if `max` is negative).
+func (h *ranking) add(v Correction, max int) {
+	if max < 0 || len(*h) < max {
+		heap.Push(h, v)
+	} else if max > 0 && v.better(&(*h)[0]) {
+		(*h)[0] = v
+		heap.Fix(h, 0)
 	}
-
-	return m
 }
 
-// levenshtein distance
-// based in part on https://rosettacode.org/wiki/Levenshtein_distance#Go, some modifications made to use one-dimensional array
-// this version usually takes about half the time as the second version, and usually less than half the time of the first version on RosettaCode
-// todo: add swap variant (e.g. `liek` -> `like`)
-func levenshtein(a, b string) float64 {
-	if a == "" {
-		return float64(len(b))
-	}
+// Returns a copy of the corrections in the ranking, best first.
+func (h ranking) best() []Correction {
+	res := append(make([]Correction, 0, len(h)), h...)
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].better(&res[j])
+	})
 
-	if b == "" {
-		return float64(len(a))
-	}
+	return res
+}
 
-	if a == b {
-		return 0
-	}
+// Weighs each candidate correction for `s`, and returns the `max` best (every candidate if `max` is negative), best
+// first. Candidates further than `target` edit distances are dropped.
+func rank(f []Correction, s string, target float64, max int) []Correction {
+	h := make(ranking, 0, len(f))
+	for _, v := range f {
+		if v.ld[0] > target {
+			continue
+		}
 
-	// row is the previous row in the LD table (contains top right at current index and top left at current index - 1)
-	prev_row := make([]uint8, len(a)+1)
-	for i := range prev_row {
-		prev_row[i] = uint8(i)
+		v.weigh(s)
+		h.add(v, max)
 	}
 
-	result := 0.0
-	// first characters aren't the same
-	var current uint8
-
-	// bottom left, starts at 1
-	var bl uint8
-
-	// go through columns first
-	for i := 1; i <= len(b); i++ {
-		// previous top left - used if letters are the same
-
-		// set first value of previous row equal to ptl
-		prev_row[0] = uint8(i)
-		current = 0
-
-		// top left
-		var tl uint8
-		// top right
-		var tr uint8
-		// bottom left
-		bl = uint8(i)
-
-		// go through each character in the row
-		for j := 1; j <= len(a); j++ {
-			// set top right equal to the value at
-			tr = prev_row[j]
-			tl = prev_row[j-1]
-
-			// in first row of array, so top values should be equal to index of item (e.g. [0 1 2 3 4 5])
-			// value of top right should then be the value of the array at the index in the current loop
-			if i == 1 {
-				tr = uint8(j)
-			}
+	return h.best()
+}
 
-			// characters are the same - use previous top left value
-			if a[j-1] == b[i-1] {
-				current = tl
-			} else {
-				current = min(tl, tr, bl) + 1
-
-				// todo: verify this works correctly - hard to reason about
-				if (j < len(a) && i < len(b)) && (j+1 < len(a) && i+1 < len(b)) {
-					// transpositions
-					// bounds check for transposition indexing
-					if a[j-1] == b[i] && a[j] == b[i-1] {
-						current = tl
-					}
-				}
-			}
+// returns the minimum of a set of numbers
+func min[T int | uint8 | float64](v ...T) T {
+	m := v[0]
 
-			// set the previous top left value equal to
-			prev_row[j] = current
-			bl = current
+	for _, k := range v {
+		if k < m {
+			m = k
 		}
-
-		result = float64(current)
 	}
 
-	return result
+	return m
 }
 
-func levenshtein_with_operations(a, b string) [4]float64 {
+// Counts the edits between `x` and `y` the way corrections have always been weighed. The distance is the OSA distance,
+// but the breakdown into substitutions, insertions/deletions and transpositions follows the ranking's own backtrace,
+// which the weights were tuned against, rather than the alignment reported by Edits.
+func levenshtein_with_operations(x, y string) [4]float64 {
+	a, b := []rune(x), []rune(y)
 	results := [4]float64{0, 0, 0, 0}
 
 	// basic cases - empty string edit distance equal to length of other string b/c only n insertions needed
-	if a == "" || b == "" {
+	if len(a) == 0 || len(b) == 0 {
 		return [4]float64{(float64(max(len(a), len(b))))}
 	}
 
 	// same string, no edit distance
-	if a == b {
+	if x == y {
 		return results
 	}
 
@@ -1,20 +1,17 @@
 package spell
 
 import (
-	"bytes"
 	"fmt"
-	"io/ioutil"
+	"reflect"
 	"testing"
-
-	txt "github.com/hvlck/txt"
 )
 
 type LdResult struct {
 	one, two string
-	dist     uint8
+	dist     float64
 }
 
-func Testld(t *testing.T) {
+func TestLd(t *testing.T) {
 	results := []LdResult{
 		{
 			one:  "burn",
@@ -29,9 +26,9 @@ func Testld(t *testing.T) {
 	}
 
 	for _, v := range results {
-		l := ld(v.one, v.two)
+		l := Levenshtein.Distance(v.one, v.two)
 		if l != v.dist {
-			t.Fatalf(v.one, v.two, v.dist)
+			t.Fatalf("%v -> %v: expected %v, got %v", v.one, v.two, v.dist, l)
 		}
 	}
 }
@@ -115,7 +112,7 @@ const two = "antidisestablishmentarianism"
 // BenchmarkLd/rosetta_code_slice-8        	 1000000	         0.03225 ns/op	       0 B/op	       0 allocs/op
 // BenchmarkLd/txt-8                       	 1000000	         0.0007500 ns/op	       0 B/op	       0 allocs/op
 // BenchmarkSpellcheck-8                   	 1000000	        10.12 ns/op	       0 B/op	       0 allocs/op
-func Benchmarkld(b *testing.B) {
+func BenchmarkLd(b *testing.B) {
 	b.SetParallelism(1)
 	b.Run("rosetta code loop", func(b *testing.B) {
 		rt(one, two)
@@ -128,7 +125,7 @@ func Benchmarkld(b *testing.B) {
 	})
 
 	b.Run("txt", func(b *testing.B) {
-		ld(one, two)
+		Levenshtein.Distance(one, two)
 		b.StopTimer()
 	})
 }
@@ -136,18 +133,18 @@ func Benchmarkld(b *testing.B) {
 func TestWeigh(t *testing.T) {
 	c := Correction{
 		Word: "typo",
-		ld:   ld("typo", "testing"),
+		ld:   operations(OSA, nil, "typo", "testing"),
 	}
 
 	c.weigh("testing")
 }
 func TestPrefixLength(t *testing.T) {
-	vals := []uint8{
+	vals := []int{
 		PrefixLength("tree", "trees"),
 		PrefixLength("grant", "grace"),
 		PrefixLength("hammer", "hankering"),
 	}
-	answers := []uint8{4, 3, 2}
+	answers := []int{4, 3, 2}
 
 	for i, v := range vals {
 		if v != answers[i] {
@@ -179,7 +176,7 @@ func TestKeyProximity(t *testing.T) {
 		KeyProximity('1', '.'),
 		KeyProximity('b', 'w'),
 	}
-	answers := []uint8{1, 1, 1, 1, 6, 7, 5}
+	answers := []uint8{1, 1, 1, 1, 6, 7, 3}
 
 	for i, v := range vals {
 		if v != answers[i] {
@@ -206,15 +203,8 @@ func BenchmarkKeyProximity(b *testing.B) {
 	}
 }
 
-func TestSearch_Lev(t *testing.T) {
-}
-
 func TestPartialMatch(t *testing.T) {
-	if dErr != nil {
-		t.Fatal(dErr)
-	}
-
-	matches := PartialMatch(d.trie, "tesk", 3, 15)
+	matches := PartialMatch(d.Node, "tesk", 3, 15)
 	if len(matches) != 15 {
 		t.Fail()
 	}
@@ -233,10 +223,10 @@ func TestPartialMatch(t *testing.T) {
 	}
 
 	for i, v := range results {
-		r := PartialMatch(d.trie, i, 2, 10)
+		r := PartialMatch(d.Node, i, 2, 10)
 		if r != nil && len(r) > 0 {
-			if r[len(r)-1].Word != v {
-				t.Fatalf("expected %v, got %v (ld: %v)", v, r[len(r)-1], ld(v, i))
+			if r[0].Word != v {
+				t.Fatalf("expected %v, got %v (ld: %v)", v, r[0], OSA.Distance(v, i))
 				for _, tt := range r {
 					if tt.Word == v {
 						fmt.Println(tt)
@@ -247,74 +237,79 @@ func TestPartialMatch(t *testing.T) {
 	}
 }
 
+func TestRank(t *testing.T) {
+	all := PartialMatch(d.Node, "tesk", 2, -1)
+	if len(all) != len(search_lev(d.Node, "tesk", 2)) {
+		t.Fatalf("expected every match, got %v of %v", len(all), len(search_lev(d.Node, "tesk", 2)))
+	}
+
+	for i := 1; i < len(all); i++ {
+		if all[i].better(&all[i-1]) {
+			t.Fatalf("expected best-first order, got %v before %v", all[i-1], all[i])
+		}
+	}
+
+	// the top k are a prefix of the full ranking
+	top := PartialMatch(d.Node, "tesk", 2, 10)
+	if !reflect.DeepEqual(top, all[:10]) {
+		t.Fatalf("expected %v, got %v", all[:10], top)
+	}
+
+	few := PartialMatch(d.Node, "spelling", 0, 10)
+	if len(few) != 1 || few[0].Word != "spelling" {
+		t.Fatalf("expected only the exact match, got %v", few)
+	}
+
+	if r := PartialMatch(d.Node, "tesk", 2, 0); len(r) != 0 {
+		t.Fatalf("expected no matches, got %v", r)
+	}
+}
+
 func BenchmarkPartialMatch(b *testing.B) {
 	b.SetParallelism(1)
-	b.StopTimer()
-	if dErr != nil {
-		b.Fatal(dErr)
-	}
-	b.StartTimer()
 
-	matches := PartialMatch(d.trie, "tesk", 3, 15)
+	matches := PartialMatch(d.Node, "tesk", 3, 15)
 	if len(matches) != 15 {
 		b.Fail()
 	}
 }
 
 func TestSpellcheck(t *testing.T) {
-	results, err := Correct("wat", 3)
+	results := Correct("wat", 3)
 	fmt.Println(results)
-	if err != nil {
+	if len(results) == 0 {
 		t.Fail()
 	}
 }
 
-var d, dErr = loadTrie()
-
-type Dictionary struct {
-	trie *txt.Node
-}
+var d = NewDict()
 
-func loadTrie() (Dictionary, error) {
-	b, err := ioutil.ReadFile("./data/final.txt")
+// Returns `v`, panicking on `err`, for building indexes in variable declarations.
+func must[T any](v T, err error) T {
 	if err != nil {
-		return Dictionary{}, err
-	}
-	t := txt.NewTrie()
-
-	lines := bytes.Split(b, []byte("\n"))
-	for _, v := range lines {
-		if len(v) > 0 {
-			r := bytes.Split(v, []byte(","))
-			w := string(r[0])
-			t.Insert(w, r[1])
-			if err != nil {
-				panic(err)
-			}
-		}
+		panic(err)
 	}
-	return Dictionary{trie: t}, nil
+
+	return v
 }
 
 func BenchmarkTrieSpellcheck(b *testing.B) {
 	b.SetParallelism(1)
-	if dErr != nil {
-		b.Fatal(dErr)
-	}
-
-	f := PartialMatch(d.trie, "wat", 5, 15)
 
-	if len(f) != 15 {
-		b.Fail()
+	for i := 0; i < b.N; i++ {
+		f := PartialMatch(d.Node, "wat", 5, 15)
+		if len(f) != 15 {
+			b.Fail()
+		}
 	}
 }
 
 func BenchmarkSpellcheck(b *testing.B) {
 	b.SetParallelism(1)
 
-	_, err := Correct("wat", 3)
+	results := Correct("wat", 3)
 	b.StopTimer()
-	if err != nil {
+	if len(results) == 0 {
 		b.Fail()
 	}
 }
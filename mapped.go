@@ -0,0 +1,324 @@
+package spell
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"math"
+	"sort"
+
+	txt "github.com/hvlck/txt"
+)
+
+// Mapped trie layout. Unlike snapshots, the image is searched in place, so nodes are stored as fixed-size records
+// addressed by their byte offset in the image. All integers are little-endian. Offsets are uint32, so an image is at
+// most 4 GiB.
+//
+//	magic     "SPMT"
+//	version   uint16
+//	reserved  uint16
+//	nodes     uint32, number of nodes
+//	checksum  uint32, CRC-32 (IEEE) of every byte after the header
+//
+// The root node follows the header. Each node is:
+//
+//	kids      uint16, number of children
+//	flags     uint8, bit 0 set if a word ends at this node
+//	reserved  uint8
+//	frequency 16 bytes, only present for nodes where a word ends: the word's count (NaN if it has none) and its
+//	          log-probability, each as float64 bits
+//	children  kids × (uint32 character byte, uint32 offset of child node), sorted by character
+const (
+	mapped_magic   = "SPMT"
+	mapped_version = 2
+	mapped_header  = 16
+
+	mapped_terminal = 1 << 0
+)
+
+var (
+	ErrNotMapped      = errors.New("spell: not a mapped dictionary")
+	ErrMappedTooLarge = errors.New("spell: dictionary too large for a mapped image")
+)
+
+// Largest image whose node offsets fit in a uint32; a variable so tests can lower it.
+var mapped_max_size uint64 = math.MaxUint32
+
+// Size of a node record in the mapped layout.
+func mapped_size(n *txt.Node) uint32 {
+	size := uint32(4 + 8*len(mapped_kids(n)))
+	if _, ok := n.Kids['*']; ok {
+		size += freq_data_len
+	}
+
+	return size
+}
+
+// Returns the children of `n` other than its terminal node, sorted by character.
+func mapped_kids(n *txt.Node) []rune {
+	keys := make([]rune, 0, len(n.Kids))
+	for rn := range n.Kids {
+		if rn != '*' {
+			keys = append(keys, rn)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
+
+// WriteMapped writes the dictionary as a flat, read-only trie image that OpenMapped can search in place without
+// rebuilding the trie. ErrMappedTooLarge is returned, before anything is written, if the image would exceed 4 GiB.
+//
+// A snapshot written by WriteTo cannot be mapped instead: to stay compact, it stores words as one stream of
+// prefix-compressed, varint-encoded entries, so reaching any word means decoding every entry before it, and it holds
+// no trie nodes to search. The mapped image stores each node at a fixed offset instead, at the cost of a larger,
+// separate file.
+func (d *Dict) WriteMapped(w io.Writer) (int64, error) {
+	// first pass: offsets of every node, in the order they will be written
+	offsets := map[*txt.Node]uint32{}
+	var next uint64 = mapped_header
+	var assign func(n *txt.Node)
+	assign = func(n *txt.Node) {
+		if next > mapped_max_size {
+			return
+		}
+
+		offsets[n] = uint32(next)
+		next += uint64(mapped_size(n))
+		for _, rn := range mapped_kids(n) {
+			assign(n.Kids[rn])
+		}
+	}
+	assign(d.Node)
+
+	if next > mapped_max_size {
+		return 0, ErrMappedTooLarge
+	}
+
+	// node records are written twice: once to compute the checksum for the header, then to `w`
+	var body io.Writer
+	buf := make([]byte, 8)
+	var err error
+	var write func(n *txt.Node)
+	write = func(n *txt.Node) {
+		if err != nil {
+			return
+		}
+
+		kids := mapped_kids(n)
+		end, terminal := n.Kids['*']
+
+		rec := make([]byte, 0, mapped_size(n))
+		binary.LittleEndian.PutUint16(buf, uint16(len(kids)))
+		rec = append(rec, buf[:2]...)
+		if terminal {
+			rec = append(rec, mapped_terminal, 0)
+			rec = append(rec, encode_freq(decode_freq(end.Data))...)
+		} else {
+			rec = append(rec, 0, 0)
+		}
+
+		for _, rn := range kids {
+			binary.LittleEndian.PutUint32(buf, uint32(rn))
+			binary.LittleEndian.PutUint32(buf[4:], offsets[n.Kids[rn]])
+			rec = append(rec, buf...)
+		}
+
+		if _, err = body.Write(rec); err != nil {
+			return
+		}
+
+		for _, rn := range kids {
+			write(n.Kids[rn])
+		}
+	}
+
+	sum := crc32.NewIEEE()
+	body = sum
+	write(d.Node)
+
+	header := make([]byte, mapped_header)
+	copy(header, mapped_magic)
+	binary.LittleEndian.PutUint16(header[4:], mapped_version)
+	binary.LittleEndian.PutUint32(header[8:], uint32(len(offsets)))
+	binary.LittleEndian.PutUint32(header[12:], sum.Sum32())
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(header); err != nil {
+		return 0, err
+	}
+
+	body = bw
+	write(d.Node)
+	if err == nil {
+		err = bw.Flush()
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(next), nil
+}
+
+// MappedDict is a read-only dictionary searched directly from a mapped trie image written by Dict.WriteMapped.
+// When opened with OpenMapped, the image is memory-mapped where the platform supports it, so the pages are shared by
+// every process using the same file. A MappedDict is safe for concurrent use.
+type MappedDict struct {
+	// Edit distance or similarity used to find and rank corrections; the zero value is OSA.
+	Metric Metric
+	// Costs of each edit under the Keyboard metric; nil uses DefaultCosts.
+	Costs *Costs
+
+	data  []byte
+	close func() error
+}
+
+// NewMappedDict searches a mapped trie image held in memory. `b` must not be modified while the dictionary is in
+// use.
+func NewMappedDict(b []byte) (*MappedDict, error) {
+	if len(b) < mapped_header || string(b[:4]) != mapped_magic {
+		return nil, ErrNotMapped
+	}
+
+	if binary.LittleEndian.Uint16(b[4:]) != mapped_version {
+		return nil, ErrSnapshotVersion
+	}
+
+	return &MappedDict{data: b}, nil
+}
+
+// Close releases the mapped image. The dictionary must not be used afterwards.
+func (m *MappedDict) Close() error {
+	if m.close == nil {
+		return nil
+	}
+
+	err := m.close()
+	m.close = nil
+	m.data = nil
+	return err
+}
+
+// Verify checks the image against its checksum. This reads every page of the image, so it is not done when the
+// dictionary is opened.
+func (m *MappedDict) Verify() error {
+	if crc32.ChecksumIEEE(m.data[mapped_header:]) != binary.LittleEndian.Uint32(m.data[12:]) {
+		return ErrSnapshotChecksum
+	}
+
+	return nil
+}
+
+// A node record in the mapped image.
+type mapped_node struct {
+	terminal bool
+	// frequency data, in the same format as a Dict's terminal nodes
+	freq []byte
+	// child records, 8 bytes each
+	kids []byte
+}
+
+// Reads the node at `off`. Out of range offsets, which can only come from a corrupt image, read as empty nodes.
+func (m *MappedDict) node(off uint32) mapped_node {
+	if uint64(off)+4 > uint64(len(m.data)) {
+		return mapped_node{}
+	}
+
+	n := mapped_node{}
+	nkids := uint64(binary.LittleEndian.Uint16(m.data[off:]))
+	pos := uint64(off) + 4
+	if m.data[off+2]&mapped_terminal != 0 {
+		if pos+freq_data_len > uint64(len(m.data)) {
+			return mapped_node{}
+		}
+
+		n.terminal = true
+		n.freq = m.data[pos : pos+freq_data_len]
+		pos += freq_data_len
+	}
+
+	if pos+nkids*8 > uint64(len(m.data)) {
+		return mapped_node{}
+	}
+	n.kids = m.data[pos : pos+nkids*8]
+
+	return n
+}
+
+// Returns the character and offset of the i-th child.
+func (n mapped_node) kid(i int) (byte, uint32) {
+	return byte(binary.LittleEndian.Uint32(n.kids[i*8:])), binary.LittleEndian.Uint32(n.kids[i*8+4:])
+}
+
+// Finds the child for character `c`.
+func (n mapped_node) find(c byte) (uint32, bool) {
+	nkids := len(n.kids) / 8
+	i := sort.Search(nkids, func(i int) bool {
+		k, _ := n.kid(i)
+		return k >= c
+	})
+
+	if i < nkids {
+		if k, off := n.kid(i); k == c {
+			return off, true
+		}
+	}
+
+	return 0, false
+}
+
+// Contains reports whether `word` is in the dictionary.
+func (m *MappedDict) Contains(word string) bool {
+	if len(word) == 0 {
+		return false
+	}
+
+	var cur uint32 = mapped_header
+	n := m.node(cur)
+	for i := 0; i < len(word); i++ {
+		off, ok := n.find(word[i])
+		// children always follow their parent, anything else is a corrupt image
+		if !ok || off <= cur {
+			return false
+		}
+		cur = off
+		n = m.node(off)
+	}
+
+	return n.terminal
+}
+
+// Candidates returns all words in the image within `limit` edit distances of `s`, in the same way as the trie search.
+func (m *MappedDict) Candidates(s string, limit float64) []Correction {
+	c, _ := m.candidates_context(context.Background(), s, limit)
+	return c
+}
+
+func (m *MappedDict) candidates_context(ctx context.Context, s string, limit float64) ([]Correction, bool) {
+	res := []Correction{}
+	r := new_lev_rows(s, m.Metric, m.Costs)
+	r.done = ctx.Done()
+	r.walk_mapped(m, mapped_header, 0, limit, func(c Correction) {
+		res = append(res, c)
+	})
+
+	return res, r.stopped
+}
+
+// Version returns a number that changes whenever the metric or its costs are changed, the only changes a read-only
+// dictionary can see.
+func (m *MappedDict) Version() uint64 {
+	return metric_version(m.Metric, m.Costs)
+}
+
+// PartialMatch is the equivalent of the package-level PartialMatch for a mapped dictionary.
+func (m *MappedDict) PartialMatch(s string, target float64, max int) []Correction {
+	return Match(m, s, target, max)
+}
@@ -0,0 +1,16 @@
+//go:build !(aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris)
+
+package spell
+
+import "os"
+
+// OpenMapped reads a trie image written by Dict.WriteMapped. Memory-mapping is not supported on this platform, so
+// the image is read into memory instead.
+func OpenMapped(path string) (*MappedDict, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMappedDict(b)
+}
@@ -0,0 +1,134 @@
+package spell
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Returns the words, frequencies and distances of a set of candidate corrections, sorted.
+func candidate_words(c []Correction) []string {
+	r := make([]string, 0, len(c))
+	for _, v := range c {
+		r = append(r, fmt.Sprintf("%v/%v/%v", v.Word, v.Frequency, v.ld))
+	}
+	sort.Strings(r)
+
+	return r
+}
+
+func TestMapped(t *testing.T) {
+	d, err := Load(strings.NewReader("apple,10\napples,2.5\napply,4\nbanana,3\ncafé,1\n"), CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Insert("aple", nil)
+
+	buf := bytes.Buffer{}
+	n, err := d.WriteMapped(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != int64(buf.Len()) {
+		t.Fatalf("WriteMapped reported %v bytes, wrote %v", n, buf.Len())
+	}
+
+	path := filepath.Join(t.TempDir(), "words.spmt")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := OpenMapped(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []string{"apple", "apples", "aple", "café"} {
+		if !m.Contains(v) {
+			t.Fatalf("expected %q to be in dictionary", v)
+		}
+	}
+
+	for _, v := range []string{"", "app", "applesauce", "cafe"} {
+		if m.Contains(v) {
+			t.Fatalf("expected %q not to be in dictionary", v)
+		}
+	}
+
+	for _, s := range []string{"aple", "appel", "banan", "xyz"} {
+		want := candidate_words(search_lev(d.Node, s, 2))
+		got := candidate_words(m.Candidates(s, 2))
+		if strings.Join(want, " ") != strings.Join(got, " ") {
+			t.Fatalf("%v: expected candidates %v, got %v", s, want, got)
+		}
+	}
+
+	r := m.PartialMatch("appel", 2, 3)
+	if len(r) != 3 {
+		t.Fatalf("expected 3 results, got %v", r)
+	}
+}
+
+func TestMappedErrors(t *testing.T) {
+	if _, err := NewMappedDict([]byte("SPEL\x01\x00")); err != ErrNotMapped {
+		t.Fatalf("expected ErrNotMapped, got %v", err)
+	}
+
+	buf := bytes.Buffer{}
+	if _, err := NewDict().WriteMapped(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.Bytes()
+	b[len(b)/2] ^= 0xff
+	m, err := NewMappedDict(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Verify(); err != ErrSnapshotChecksum {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+
+	// corrupt images must not crash searches
+	m.PartialMatch("tesk", 2, 5)
+
+	// images whose offsets would overflow are refused before anything is written
+	defer func(max uint64) { mapped_max_size = max }(mapped_max_size)
+	mapped_max_size = uint64(buf.Len()) - 1
+	buf.Reset()
+	if _, err := NewDict().WriteMapped(&buf); err != ErrMappedTooLarge || buf.Len() != 0 {
+		t.Fatalf("expected ErrMappedTooLarge with nothing written, got %v and %v bytes", err, buf.Len())
+	}
+}
+
+func BenchmarkOpenMapped(b *testing.B) {
+	buf := bytes.Buffer{}
+	if _, err := NewDict().WriteMapped(&buf); err != nil {
+		b.Fatal(err)
+	}
+
+	path := filepath.Join(b.TempDir(), "words.spmt")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		m, err := OpenMapped(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		m.Close()
+	}
+}
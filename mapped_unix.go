@@ -0,0 +1,44 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package spell
+
+import (
+	"os"
+	"syscall"
+)
+
+// OpenMapped memory-maps a trie image written by Dict.WriteMapped. The mapping is read-only and shared, so every
+// process opening the same file uses the same physical pages.
+func OpenMapped(path string) (*MappedDict, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() < mapped_header {
+		return nil, ErrNotMapped
+	}
+
+	b, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := NewMappedDict(b)
+	if err != nil {
+		syscall.Munmap(b)
+		return nil, err
+	}
+
+	m.close = func() error {
+		return syscall.Munmap(b)
+	}
+
+	return m, nil
+}
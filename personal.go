@@ -0,0 +1,210 @@
+package spell
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	txt "github.com/hvlck/txt"
+)
+
+// Personal is a user's own word list, editable at runtime. Words added to it are searchable immediately, and are
+// persisted as a plain word list (one word per line, the same format as `data/words.txt`).
+// Use it as a layer to merge it with other dictionaries:
+//
+//	home, err := os.UserHomeDir()
+//	p, err := spell.OpenPersonal(filepath.Join(home, ".spell", "personal.txt"))
+//	l := spell.NewLayers(
+//		spell.Layer{Name: "base", Source: spell.NewDict()},
+//		spell.Layer{Name: "personal", Source: p, Priority: 1},
+//	)
+//
+// Personal is safe for concurrent use.
+type Personal struct {
+	mu   sync.RWMutex
+	dict *Dict
+	// file the words are saved to, empty if the dictionary is only held in memory
+	path string
+}
+
+// NewPersonal creates an empty personal dictionary held only in memory.
+func NewPersonal() *Personal {
+	return &Personal{dict: &Dict{Node: txt.NewTrie()}}
+}
+
+// OpenPersonal loads the personal dictionary saved at `path`. A missing file is treated as an empty dictionary, and
+// is created on the first change.
+func OpenPersonal(path string) (*Personal, error) {
+	p := NewPersonal()
+	p.path = path
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := p.dict.Load(f, Words); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Add adds words to the dictionary and saves it. No words are added if any of them are invalid, or if saving fails.
+func (p *Personal) Add(words ...string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, w := range words {
+		if len(w) == 0 {
+			return ErrEmptyWord
+		}
+
+		if !valid_word(w) {
+			return ErrInvalidWord
+		}
+	}
+
+	added := []string{}
+	for _, w := range words {
+		if !p.dict.Contains(w) {
+			p.dict.Insert(w, nil)
+			added = append(added, w)
+		}
+	}
+
+	if len(added) == 0 {
+		return nil
+	}
+
+	if err := p.save(); err != nil {
+		for _, w := range added {
+			p.dict.Remove(w)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Remove removes words from the dictionary and saves it. Words that are not in the dictionary are ignored. No words
+// are removed if saving fails.
+func (p *Personal) Remove(words ...string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	removed := []string{}
+	for _, w := range words {
+		if p.dict.Remove(w) {
+			removed = append(removed, w)
+		}
+	}
+
+	if len(removed) == 0 {
+		return nil
+	}
+
+	if err := p.save(); err != nil {
+		for _, w := range removed {
+			p.dict.Insert(w, nil)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Contains reports whether `word` is in the dictionary.
+func (p *Personal) Contains(word string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.dict.Contains(word)
+}
+
+// Version returns a number that changes whenever words are added or removed.
+func (p *Personal) Version() uint64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.dict.Version()
+}
+
+// List returns every word in the dictionary, in byte order.
+func (p *Personal) List() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	words := []string{}
+	p.dict.Walk(func(word string, _ float64) bool {
+		words = append(words, word)
+		return true
+	})
+
+	return words
+}
+
+// Candidates returns all personal words within `limit` edit distances of `s`.
+func (p *Personal) Candidates(s string, limit float64) []Correction {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.dict.Candidates(s, limit)
+}
+
+func (p *Personal) candidates_context(ctx context.Context, s string, limit float64) ([]Correction, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.dict.candidates_context(ctx, s, limit)
+}
+
+// PartialMatch returns the `max` best corrections for `s` within `target` edit distances among the personal words.
+func (p *Personal) PartialMatch(s string, target float64, max int) []Correction {
+	return Match(p, s, target, max)
+}
+
+// Writes the word list to `p.path`. The list is written to a temporary file in the same directory, which then
+// replaces the old list, so readers never see a partially written file.
+func (p *Personal) save() error {
+	if len(p.path) == 0 {
+		return nil
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(p.path), "."+filepath.Base(p.path)+".*")
+	if err != nil {
+		return err
+	}
+	// no-op once renamed
+	defer os.Remove(f.Name())
+
+	w := bufio.NewWriter(f)
+	p.dict.Walk(func(word string, _ float64) bool {
+		_, err = w.WriteString(word + "\n")
+		return err == nil
+	})
+
+	if err == nil {
+		err = w.Flush()
+	}
+
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), p.path)
+}
@@ -0,0 +1,122 @@
+package spell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPersonal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "personal.txt")
+
+	p, err := OpenPersonal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Add("kubectl", "grafana", "kubectx"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Add("fine", "bad*word"); err != ErrInvalidWord || p.Contains("fine") {
+		t.Fatalf("expected invalid batch to be rejected, got %v", err)
+	}
+
+	if !p.Contains("kubectl") || p.Contains("kube") {
+		t.Fatal("expected added words to be searchable")
+	}
+
+	r := p.PartialMatch("grafna", 1, 5)
+	if len(r) == 0 || r[0].Word != "grafana" {
+		t.Fatalf("expected grafana to be suggested, got %v", r)
+	}
+
+	if err := p.Remove("kubectx", "missing"); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "grafana\nkubectl\n" {
+		t.Fatalf("unexpected saved word list %q", b)
+	}
+
+	// no temporary files are left behind
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Fatalf("expected only the word list in %v, got %v", filepath.Dir(path), entries)
+	}
+
+	reopened, err := OpenPersonal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(reopened.List(), ",") != "grafana,kubectl" {
+		t.Fatalf("unexpected words after reopening: %v", reopened.List())
+	}
+}
+
+func TestPersonalLayer(t *testing.T) {
+	p := NewPersonal()
+	l := NewLayers(
+		Layer{Name: "base", Source: NewDict()},
+		Layer{Name: "personal", Source: p, Priority: 1},
+	)
+
+	before := l.PartialMatch("grafana", 0, 1)
+	if len(before) > 0 && before[0].Word == "grafana" {
+		t.Fatal("grafana should not be in the base dictionary")
+	}
+
+	p.Add("grafana")
+	r := l.PartialMatch("grafana", 0, 1)
+	if len(r) != 1 || r[0].Word != "grafana" || r[0].Layer != "personal" {
+		t.Fatalf("expected accepted word to match exactly, got %v", r)
+	}
+}
+
+func TestDictRemove(t *testing.T) {
+	d, _ := Load(strings.NewReader("car\ncart\ncat\n"), Words)
+
+	if !d.Remove("car") || d.Remove("car") || d.Remove("ca") {
+		t.Fatal("expected car to be removed once")
+	}
+
+	if d.Contains("car") || !d.Contains("cart") || !d.Contains("cat") {
+		t.Fatal("removing car should keep cart and cat")
+	}
+
+	d.Remove("cart")
+	if _, ok := d.Kids['c'].Kids['a'].Kids['r']; ok {
+		t.Fatal("expected empty branch to be pruned")
+	}
+}
+
+func TestPersonalSaveError(t *testing.T) {
+	dir := t.TempDir()
+	p, err := OpenPersonal(filepath.Join(dir, "personal.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Add("kubectl"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the list can no longer be saved once its directory is gone
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Add("grafana"); err == nil || p.Contains("grafana") {
+		t.Fatalf("expected a word that could not be saved not to be added, got %v", err)
+	}
+
+	if err := p.Remove("kubectl"); err == nil || !p.Contains("kubectl") {
+		t.Fatalf("expected a word that could not be saved not to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,418 @@
+package spell
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	txt "github.com/hvlck/txt"
+)
+
+// Dynamic programming rows for an edit distance search over a trie.
+// Row `k` holds the distances between the first `k` runes of the current trie path and every prefix of the query,
+// so moving one node down the trie computes at most one new row, and a row is shared by every word below the node it
+// was computed for. Trie nodes hold single bytes, so the bytes of a multi-byte rune are collected along the path, and
+// its row is only computed once the rune is complete.
+type lev_rows struct {
+	s string
+	// runes of the query
+	q      []rune
+	metric Metric
+	// costs of each edit for the Keyboard metric, nil for the unweighted metrics
+	costs *Costs
+	rows  [][]float64
+	// smallest distance in each row, or a smaller bound on every row after it
+	least []float64
+	// bytes of the current trie path
+	path []byte
+	// complete runes of the current trie path
+	runes []rune
+	// at[i] is the number of complete runes in path[:i], and ends[k] the length in bytes of the first `k` runes
+	at, ends []int
+	// for the unrestricted Damerau distance, last[k][j] is the last row up to `k` whose path rune is q[j-1],
+	// or 0 if there is none
+	last [][]int
+	// for similarity metrics, which replace the rows
+	similar *similar_rows
+
+	// closed when the search should stop, nil if it runs to completion
+	done <-chan struct{}
+	// rows computed since the search started
+	steps uint
+	// the search was stopped before visiting every node
+	stopped bool
+}
+
+func new_lev_rows(s string, m Metric, c *Costs) *lev_rows {
+	r := &lev_rows{s: s, q: []rune(s), metric: m, costs: m.costs(c), at: []int{0}, ends: []int{0}, least: []float64{0}}
+	first := make([]float64, len(r.q)+1)
+	for j := 1; j <= len(r.q); j++ {
+		first[j] = first[j-1] + r.insertion()
+	}
+
+	r.rows = [][]float64{first}
+	switch {
+	case m == Damerau:
+		r.last = [][]int{make([]int, len(r.q)+1)}
+	case m.similarity():
+		r.similar = new_similar_rows(r.q)
+	}
+
+	return r
+}
+
+// Extends the path of length `i` by `c`, and returns a lower bound on the distance of every word below the new path:
+// the smallest distance in the row of its last complete rune, since row minimums never decrease, or less when a
+// transposition costs less than a deletion, since it reads the row before the last.
+func (r *lev_rows) push(i int, c byte) float64 {
+	return r.push_shared(i, c, nil, 0)
+}
+
+// Extends the path like push, copying the first `shared`+1 distances of any new row from `o`, which searches a query
+// with the same first `shared` runes along the same path and has already extended it by `c`. Those distances only
+// depend on the shared runes, so only the rest of the row is computed.
+func (r *lev_rows) push_shared(i int, c byte, o *lev_rows, shared int) float64 {
+	r.path = append(r.path[:i], c)
+	if len(r.at) <= i+1 {
+		r.at = append(r.at, 0)
+	}
+
+	k := r.at[i]
+	if c < utf8.RuneSelf && r.ends[k] == i {
+		r.push_rune(k, rune(c), o, shared)
+		r.ends = append(r.ends[:k+1], i+1)
+		r.at[i+1] = k + 1
+		return r.least[k+1]
+	}
+
+	// bytes after the last complete rune; invalid bytes are runes of their own, as when ranging over a string
+	pending := r.path[r.ends[k]:]
+	for len(pending) > 0 && (pending[0] < utf8.RuneSelf || utf8.FullRune(pending)) {
+		ru, size := rune(pending[0]), 1
+		if ru >= utf8.RuneSelf {
+			ru, size = utf8.DecodeRune(pending)
+		}
+
+		r.push_rune(k, ru, o, shared)
+		k++
+		r.ends = append(r.ends[:k], r.ends[k-1]+size)
+		pending = pending[size:]
+	}
+
+	r.at[i+1] = k
+	return r.least[k]
+}
+
+// Extends the path of `k` complete runes by `c`, computing row `k+1`.
+func (r *lev_rows) push_rune(k int, c rune, o *lev_rows, shared int) {
+	if len(r.rows) <= k+1 {
+		r.rows = append(r.rows, make([]float64, len(r.q)+1))
+		r.least = append(r.least, 0)
+	}
+
+	if r.similar != nil {
+		r.least[k+1] = r.push_similar(k, c)
+		return
+	}
+
+	r.runes = append(r.runes[:k], c)
+
+	if r.metric == Damerau {
+		if len(r.last) <= k+1 {
+			r.last = append(r.last, make([]int, len(r.q)+1))
+		}
+
+		for j := 1; j <= len(r.q); j++ {
+			if r.q[j-1] == c {
+				r.last[k+1][j] = k + 1
+			} else {
+				r.last[k+1][j] = r.last[k][j]
+			}
+		}
+	}
+
+	prev, row := r.rows[k], r.rows[k+1]
+	row[0] = prev[0] + r.deletion()
+	least := row[0]
+	start := 1
+	if o != nil {
+		for j := 1; j <= shared; j++ {
+			row[j] = o.rows[k+1][j]
+			if row[j] < least {
+				least = row[j]
+			}
+		}
+		start = shared + 1
+	}
+
+	// last column before `j` where the query matches `c`, for the unrestricted Damerau distance
+	lc := 0
+	for j := 1; j < start; j++ {
+		if r.q[j-1] == c {
+			lc = j
+		}
+	}
+
+	deletion, insertion := r.deletion(), r.insertion()
+	for j := start; j <= len(r.q); j++ {
+		cost := 1.0
+		if r.q[j-1] == c {
+			cost = 0
+		} else if r.costs != nil {
+			cost = r.costs.substitution(c, r.q[j-1])
+		}
+
+		// deletion, insertion, substitution
+		v := prev[j] + deletion
+		if ins := row[j-1] + insertion; ins < v {
+			v = ins
+		}
+		if sub := prev[j-1] + cost; sub < v {
+			v = sub
+		}
+
+		switch r.metric {
+		case OSA, Keyboard:
+			// transposition of the last two runes
+			if k > 0 && j > 1 && c == r.q[j-2] && r.runes[k-1] == r.q[j-1] {
+				swap := cost
+				if r.costs != nil && cost != 0 {
+					swap = r.costs.Transposition
+				}
+
+				if trans := r.rows[k-1][j-2] + swap; trans < v {
+					v = trans
+				}
+			}
+		case Damerau:
+			// transposition of the last path rune that was the query's q[j-1] with the last query rune that was `c`,
+			// deleting and inserting everything between them
+			if l := r.last[k][j]; l > 0 && lc > 0 {
+				if trans := r.rows[l-1][lc-1] + float64(k-l) + 1 + float64(j-lc-1); trans < v {
+					v = trans
+				}
+			}
+
+			if cost == 0 {
+				lc = j
+			}
+		}
+
+		row[j] = v
+		if v < least {
+			least = v
+		}
+	}
+
+	// the row after this one can transpose its rune with this one, reading row `k` instead
+	if r.costs != nil {
+		if trans := r.least[k] + r.costs.Transposition; trans < least {
+			least = trans
+		}
+	}
+
+	r.least[k+1] = least
+}
+
+// Cost of typing an extra character.
+func (r *lev_rows) insertion() float64 {
+	if r.costs != nil {
+		return r.costs.Insertion
+	}
+
+	return 1
+}
+
+// Cost of leaving a character out.
+func (r *lev_rows) deletion() float64 {
+	if r.costs != nil {
+		return r.costs.Deletion
+	}
+
+	return 1
+}
+
+// Reports whether the search should stop. The done channel is only polled every few hundred rows, which keeps the
+// check cheap while still stopping within microseconds.
+func (r *lev_rows) cancelled() bool {
+	if r.done == nil || r.stopped {
+		return r.stopped
+	}
+
+	r.steps++
+	if r.steps%256 == 0 {
+		select {
+		case <-r.done:
+			r.stopped = true
+		default:
+		}
+	}
+
+	return r.stopped
+}
+
+// Distance between the path of length `i` and the whole query.
+func (r *lev_rows) distance(i int) float64 {
+	k := r.at[i]
+	// a path ending in the middle of a rune is invalid UTF-8, and each of its remaining bytes counts as one rune; the
+	// rows are computed past the end of the path, where the next push overwrites them
+	for range r.path[r.ends[k]:i] {
+		r.push_rune(k, utf8.RuneError, nil, 0)
+		k++
+	}
+
+	if r.similar != nil {
+		return r.similar_distance(k)
+	}
+
+	return r.rows[k][len(r.q)]
+}
+
+// Creates a correction for the path of length `i`, which ends a word with frequency data `data`.
+func (r *lev_rows) correction(i int, data []byte) Correction {
+	word := string(r.path[:i])
+	c := new_correction(word, operations(r.metric, r.costs, word, r.s), data)
+	c.metric, c.costs = r.metric, r.costs
+	return c
+}
+
+// Searches for all words in the trie within a fixed `limit` edit distance away from the original string `s`.
+// Subtrees are abandoned as soon as every distance in the current row exceeds `limit`.
+func search_lev(n *txt.Node, s string, limit float64) []Correction {
+	res, _ := search_lev_context(context.Background(), n, s, limit, OSA, nil)
+	return res
+}
+
+// Searches like search_lev with the metric `m` (and costs `c`, for Keyboard) until `ctx` is done, returning the
+// corrections found so far and whether the search was stopped early.
+func search_lev_context(ctx context.Context, n *txt.Node, s string, limit float64, m Metric, c *Costs) ([]Correction, bool) {
+	res := []Correction{}
+	if n == nil {
+		return res, false
+	}
+
+	r := new_lev_rows(s, m, c)
+	r.done = ctx.Done()
+	r.walk_trie(n, 0, limit, func(c Correction) {
+		res = append(res, c)
+	})
+
+	return res, r.stopped
+}
+
+// Searches like search_lev_context, splitting the subtrees of the root across `workers` goroutines (one per CPU if
+// negative). Corrections are returned in byte order, whatever the number of workers.
+func search_lev_parallel(ctx context.Context, n *txt.Node, s string, limit float64, m Metric, c *Costs, workers int) ([]Correction, bool) {
+	if n == nil {
+		return []Correction{}, false
+	}
+
+	if workers < 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	keys := make([]rune, 0, len(n.Kids))
+	for rn, v := range n.Kids {
+		// words end below the root, never at it
+		if !v.Done || len(v.Kids) != 0 {
+			keys = append(keys, rn)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+
+	// corrections found in each subtree, merged in key order once every worker is done
+	found := make([][]Correction, len(keys))
+	stopped := make([]bool, workers)
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers && w < len(keys); w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			r := new_lev_rows(s, m, c)
+			r.done = ctx.Done()
+			for i := range jobs {
+				res := []Correction{}
+				if !r.cancelled() && r.push(0, byte(keys[i])) <= limit {
+					r.walk_trie(n.Kids[keys[i]], 1, limit, func(c Correction) {
+						res = append(res, c)
+					})
+				}
+
+				sort.Slice(res, func(i, j int) bool {
+					return res[i].Word < res[j].Word
+				})
+				found[i] = res
+			}
+			stopped[w] = r.stopped
+		}(w)
+	}
+
+	for i := range keys {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	res := []Correction{}
+	for _, v := range found {
+		res = append(res, v...)
+	}
+
+	partial := false
+	for _, v := range stopped {
+		partial = partial || v
+	}
+
+	return res, partial
+}
+
+// Walks the children of `n`, whose path has length `i`, emitting every word within `limit` of the query.
+func (r *lev_rows) walk_trie(n *txt.Node, i int, limit float64, emit func(c Correction)) {
+	for rn, v := range n.Kids {
+		if r.cancelled() {
+			return
+		}
+
+		// end of a word
+		if v.Done && len(v.Kids) == 0 {
+			if i > 0 && r.distance(i) <= limit {
+				emit(r.correction(i, v.Data))
+			}
+			continue
+		}
+
+		if r.push(i, byte(rn)) <= limit {
+			r.walk_trie(v, i+1, limit, emit)
+		}
+	}
+}
+
+// Walks the mapped node at `off`, whose path has length `i`, emitting every word within `limit` of the query.
+func (r *lev_rows) walk_mapped(m *MappedDict, off uint32, i int, limit float64, emit func(c Correction)) {
+	n := m.node(off)
+	if n.terminal && i > 0 && r.distance(i) <= limit {
+		emit(r.correction(i, n.freq))
+	}
+
+	for k := 0; k < len(n.kids)/8; k++ {
+		if r.cancelled() {
+			return
+		}
+
+		c, child := n.kid(k)
+		// children always follow their parent, anything else is a corrupt image
+		if child <= off {
+			continue
+		}
+
+		if r.push(i, c) <= limit {
+			r.walk_mapped(m, child, i+1, limit, emit)
+		}
+	}
+}
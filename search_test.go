@@ -0,0 +1,143 @@
+package spell
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	txt "github.com/hvlck/txt"
+)
+
+// Unpruned search, computing the full distance to every word in the trie; reference for search_lev.
+func search_lev_exhaustive(n *txt.Node, s, b string, limit float64) []Correction {
+	res := []Correction{}
+	for rn, v := range n.Kids {
+		if v.Done && len(v.Kids) == 0 {
+			if len(b) > 0 {
+				if lev := operations(OSA, nil, b, s); lev[0] <= limit {
+					res = append(res, new_correction(b, lev, v.Data))
+				}
+			}
+			continue
+		}
+
+		res = append(res, search_lev_exhaustive(v, s, b+string([]byte{byte(rn)}), limit)...)
+	}
+
+	return res
+}
+
+func TestSearch_Lev(t *testing.T) {
+	sorted := func(c []Correction) []Correction {
+		sort.Slice(c, func(i, j int) bool { return c[i].Word < c[j].Word })
+		return c
+	}
+
+	queries := []string{"", "wat", "tesk", "korrectud", "peotryy", "recieve"}
+	for _, s := range queries {
+		all := sorted(search_lev_exhaustive(d.Node, s, "", 3))
+		for _, limit := range []float64{0, 1, 2, 3} {
+			want := []Correction{}
+			for _, v := range all {
+				if v.ld[0] <= limit {
+					want = append(want, v)
+				}
+			}
+
+			if got := sorted(search_lev(d.Node, s, limit)); !reflect.DeepEqual(got, want) {
+				t.Fatalf("%q within %v: expected %v corrections, got %v", s, limit, len(want), len(got))
+			}
+		}
+	}
+
+	if r := search_lev(nil, "wat", 2); len(r) != 0 {
+		t.Fatalf("expected no corrections from an empty trie, got %v", r)
+	}
+}
+
+// go test -bench 'Search|TrieSpellcheck' -benchmem
+//
+//	BenchmarkTrieSpellcheck (before)    2    516662492 ns/op    294625960 B/op    5605611 allocs/op
+//	BenchmarkTrieSpellcheck            25     68716802 ns/op     20041631 B/op     393851 allocs/op
+//	BenchmarkSearchExhaustive           6    270430575 ns/op     92599528 B/op    1954877 allocs/op
+//	BenchmarkSearchPruned            1078      1555165 ns/op       367980 B/op       5592 allocs/op
+func BenchmarkSearchExhaustive(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		search_lev_exhaustive(d.Node, "wat", "", 2)
+	}
+}
+
+func BenchmarkSearchPruned(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		search_lev(d.Node, "wat", 2)
+	}
+}
+
+func TestSearch_LevParallel(t *testing.T) {
+	for _, s := range []string{"", "wat", "korrectud", "peotryy"} {
+		want := search_lev(d.Node, s, 2)
+		sort.Slice(want, func(i, j int) bool { return want[i].Word < want[j].Word })
+
+		for _, workers := range []int{-1, 2, 3, 64} {
+			if got, _ := search_lev_parallel(context.Background(), d.Node, s, 2, OSA, nil, workers); !reflect.DeepEqual(got, want) {
+				t.Fatalf("%q with %v workers: expected %v corrections, got %v", s, workers, len(want), len(got))
+			}
+		}
+	}
+
+	par := &Dict{Node: d.Node, Parallelism: 4}
+	if r := Match(par, "speling", 2, 10); r[0].Word != "spelling" {
+		t.Fatalf("expected spelling, got %v", r)
+	}
+}
+
+func BenchmarkSearchParallel(b *testing.B) {
+	for _, workers := range []int{1, 2, 4, -1} {
+		b.Run(fmt.Sprint(workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				search_lev_parallel(context.Background(), d.Node, "korrectud", 3, OSA, nil, workers)
+			}
+		})
+	}
+}
+
+func TestPartialMatchContext(t *testing.T) {
+	if r, partial := PartialMatchContext(context.Background(), d.Node, "speling", 2, 10); partial || r[0].Word != "spelling" {
+		t.Fatalf("expected complete results ending in spelling, got %v (partial: %v)", r, partial)
+	}
+
+	// garbage with a distance large enough to visit the whole trie
+	garbage := strings.Repeat("qzx", 10)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	r, partial := PartialMatchContext(ctx, d.Node, garbage, 30, 10)
+	if !partial {
+		t.Fatal("expected partial results")
+	}
+
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("expected the search to stop at its deadline, took %v", elapsed)
+	}
+
+	if len(r) != 10 {
+		t.Fatalf("expected the best 10 corrections found so far, got %v", len(r))
+	}
+
+	// sources that stop early pass the flag through parallel searches and layers
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	par := &Dict{Node: d.Node, Parallelism: 4}
+	l := NewLayers(Layer{Name: "base", Source: par}, Layer{Name: "list", Source: Linear{"cat"}})
+	for _, src := range []CandidateSource{d, par, l, Linear{"cat"}} {
+		if _, partial := MatchContext(cancelled, src, "cat", 2, 10); !partial {
+			t.Fatalf("%T: expected partial results", src)
+		}
+	}
+}
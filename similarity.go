@@ -0,0 +1,295 @@
+package spell
+
+// Similarity metrics compare words by the characters they share rather than by the edits between them, so short words
+// with one typo are not ranked as far from their correction as an edit distance relative to their length would make
+// them. Their distances are 1 minus their similarity, between 0 for equal words and 1 for words with nothing in
+// common.
+
+// Scaling factor of the Jaro-Winkler prefix bonus, and the longest prefix it rewards.
+const (
+	winkler_scale  = .1
+	winkler_prefix = 4
+)
+
+// Bigram boundary markers, which are not valid runes.
+const (
+	gram_start rune = -1
+	gram_end   rune = -2
+)
+
+type bigram [2]rune
+
+// Reports whether `m` is a similarity metric rather than an edit distance.
+func (m Metric) similarity() bool {
+	return m == JaroWinkler || m == Dice || m == Jaccard
+}
+
+// Similarity of `a` and `b` under the similarity metric `m`, between 0 and 1.
+func similarity(m Metric, a, b []rune) float64 {
+	switch m {
+	case JaroWinkler:
+		return jaro_winkler(a, b)
+	case Dice, Jaccard:
+		return ngram_similarity(m, a, b)
+	}
+
+	return 0
+}
+
+// Similarity returns how alike `a` and `b` are under the metric, from 0 for words with nothing in common to 1 for
+// equal words. For similarity metrics, it is 1 minus their distance; for edit distances, the distance is divided by
+// the length of the longer word, in runes.
+func (m Metric) Similarity(a, b string) float64 {
+	if m.similarity() {
+		return similarity(m, []rune(a), []rune(b))
+	}
+
+	n := len([]rune(a))
+	if l := len([]rune(b)); l > n {
+		n = l
+	}
+
+	if n == 0 {
+		return 1
+	}
+
+	if s := 1 - m.Distance(a, b)/float64(n); s > 0 {
+		return s
+	}
+
+	return 0
+}
+
+// Jaro-Winkler similarity: the Jaro similarity, which counts the runes of each word found near the same position in
+// the other, raised for words sharing a prefix of up to 4 runes.
+func jaro_winkler(a, b []rune) float64 {
+	return jaro_winkler_scratch(a, b, nil, nil)
+}
+
+// Jaro-Winkler similarity, reusing `ma` and `mb` to mark matched runes if they are long enough.
+func jaro_winkler_scratch(a, b []rune, ma, mb []bool) float64 {
+	j := jaro(a, b, ma, mb)
+
+	l := 0
+	for l < len(a) && l < len(b) && l < winkler_prefix && a[l] == b[l] {
+		l++
+	}
+
+	return j + float64(l)*winkler_scale*(1-j)
+}
+
+func jaro(a, b []rune, matched_a, matched_b []bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	// runes match if they are equal and no further apart than the window
+	window := max(len(a), len(b))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matched_a, matched_b = clear_flags(matched_a, len(a)), clear_flags(matched_b, len(b))
+	m := 0
+	for i := range a {
+		for j := max(0, i-window); j < len(b) && j <= i+window; j++ {
+			if !matched_b[j] && a[i] == b[j] {
+				matched_a[i], matched_b[j] = true, true
+				m++
+				break
+			}
+		}
+	}
+
+	if m == 0 {
+		return 0
+	}
+
+	// matched runes in a different order, counted twice
+	t, j := 0, 0
+	for i := range a {
+		if !matched_a[i] {
+			continue
+		}
+
+		for !matched_b[j] {
+			j++
+		}
+
+		if a[i] != b[j] {
+			t++
+		}
+		j++
+	}
+
+	mf := float64(m)
+	return (mf/float64(len(a)) + mf/float64(len(b)) + (mf-float64(t/2))/mf) / 3
+}
+
+// Counts the bigrams of `s`, including the ones made with its start and end.
+func bigrams(s []rune) map[bigram]int {
+	g := make(map[bigram]int, len(s)+1)
+	prev := gram_start
+	for _, c := range s {
+		g[bigram{prev, c}]++
+		prev = c
+	}
+	g[bigram{prev, gram_end}]++
+
+	return g
+}
+
+// Dice coefficient or Jaccard index of the bigrams of `a` and `b`, counted with repetitions.
+func ngram_similarity(m Metric, a, b []rune) float64 {
+	ga := bigrams(a)
+	shared := 0
+	for g, n := range bigrams(b) {
+		shared += min(n, ga[g])
+	}
+
+	return ngram_score(m, shared, len(a)+1, len(b)+1)
+}
+
+// Similarity of two words with `na` and `nb` bigrams, `shared` of which they have in common.
+func ngram_score(m Metric, shared, na, nb int) float64 {
+	if m == Jaccard {
+		return float64(shared) / float64(na+nb-shared)
+	}
+
+	return 2 * float64(shared) / float64(na+nb)
+}
+
+// Returns `n` false flags, reusing `f` if it is long enough.
+func clear_flags(f []bool, n int) []bool {
+	if cap(f) < n {
+		return make([]bool, n)
+	}
+
+	f = f[:n]
+	for i := range f {
+		f[i] = false
+	}
+
+	return f
+}
+
+// State of a similarity search along a trie path: the bigrams and runes of the path shared with the query, from
+// which the similarity of the path and an upper bound on the similarity of every word below it are derived.
+type similar_rows struct {
+	grams map[bigram]int
+	runes map[rune]int
+	// bigrams and runes of the path counted so far
+	used_grams map[bigram]int
+	used_runes map[rune]int
+	// shared[k] is the number of bigrams within the first `k` runes of the path shared with the query, and excess[k]
+	// the number of those runes missing from the query
+	shared, excess []int
+	// number of path runes counted in used_grams and used_runes
+	counted int
+	// scratch space for Jaro-Winkler
+	matched_a, matched_b []bool
+}
+
+func new_similar_rows(q []rune) *similar_rows {
+	s := &similar_rows{
+		grams:      bigrams(q),
+		runes:      map[rune]int{},
+		used_grams: map[bigram]int{},
+		used_runes: map[rune]int{},
+		shared:     []int{0},
+		excess:     []int{0},
+	}
+
+	for _, c := range q {
+		s.runes[c]++
+	}
+
+	return s
+}
+
+// Bigram ending at rune `k` of `path`, 0-based.
+func path_gram(path []rune, k int) bigram {
+	if k == 0 {
+		return bigram{gram_start, path[0]}
+	}
+
+	return bigram{path[k-1], path[k]}
+}
+
+// Extends the path of `k` runes by `c` for a similarity metric, and returns a lower bound on the distance to the query
+// of every word below the new path.
+func (r *lev_rows) push_similar(k int, c rune) float64 {
+	s := r.similar
+	// forget the runes of the previous path from `k` on
+	for ; s.counted > k; s.counted-- {
+		s.used_grams[path_gram(r.runes, s.counted-1)]--
+		s.used_runes[r.runes[s.counted-1]]--
+	}
+
+	r.runes = append(r.runes[:k], c)
+	g := path_gram(r.runes, k)
+	s.used_grams[g]++
+	s.used_runes[c]++
+	s.counted = k + 1
+
+	shared, excess := s.shared[k], s.excess[k]
+	if s.used_grams[g] <= s.grams[g] {
+		shared++
+	}
+	if s.used_runes[c] > s.runes[c] {
+		excess++
+	}
+
+	s.shared = append(s.shared[:k+1], shared)
+	s.excess = append(s.excess[:k+1], excess)
+
+	n := len(r.q)
+	switch r.metric {
+	case Dice, Jaccard:
+		// each further rune adds one bigram, shared at best, until every bigram of the query is shared
+		return 1 - ngram_score(r.metric, n+1, k+1+n+1-shared, n+1)
+	case JaroWinkler:
+		if n == 0 {
+			return 1
+		}
+
+		// runes missing from the query never match, so the more there are, the smaller the share of the word's runes
+		// that can match; every other part of the similarity may still be perfect
+		j := (float64(n)/float64(max(k+1, n+excess)) + 2) / 3
+
+		prefix := 0
+		for prefix <= k && prefix < n && prefix < winkler_prefix && r.runes[prefix] == r.q[prefix] {
+			prefix++
+		}
+		if prefix == k+1 {
+			// the prefix may still grow
+			prefix = min(winkler_prefix, n)
+		}
+
+		return 1 - (j + float64(prefix)*winkler_scale*(1-j))
+	}
+
+	return 0
+}
+
+// Distance between the first `k` runes of the path and the query under a similarity metric.
+func (r *lev_rows) similar_distance(k int) float64 {
+	s := r.similar
+	switch r.metric {
+	case Dice, Jaccard:
+		shared := s.shared[k]
+		if k > 0 && s.grams[bigram{r.runes[k-1], gram_end}] > 0 || k == 0 && s.grams[bigram{gram_start, gram_end}] > 0 {
+			shared++
+		}
+
+		return 1 - ngram_score(r.metric, shared, k+1, len(r.q)+1)
+	case JaroWinkler:
+		return 1 - jaro_winkler_scratch(r.runes[:k], r.q, s.matched_a, s.matched_b)
+	}
+
+	return 0
+}
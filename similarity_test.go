@@ -0,0 +1,122 @@
+package spell
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	cases := []struct {
+		m    Metric
+		a, b string
+		want float64
+	}{
+		// reference values from Winkler's paper
+		{JaroWinkler, "MARTHA", "MARHTA", .961},
+		{JaroWinkler, "DWAYNE", "DUANE", .84},
+		{JaroWinkler, "DIXON", "DICKSONX", .813},
+		{JaroWinkler, "café", "cafe", .883},
+		{JaroWinkler, "", "", 1},
+		{JaroWinkler, "abc", "", 0},
+		{JaroWinkler, "abc", "xyz", 0},
+		// ^n ht t$ are shared, out of 6 bigrams each
+		{Dice, "night", "nacht", .5},
+		{Jaccard, "night", "nacht", .333},
+		{Dice, "привет", "привет", 1},
+		{Dice, "", "", 1},
+		{Jaccard, "a", "b", 0},
+		{Levenshtein, "kitten", "sitting", .571},
+		{OSA, "", "", 1},
+	}
+
+	for _, c := range cases {
+		if got := c.m.Similarity(c.a, c.b); math.Abs(got-c.want) > .001 {
+			t.Fatalf("%v(%q, %q): expected %v, got %v", c.m, c.a, c.b, c.want, got)
+		}
+
+		if got := c.m.Similarity(c.b, c.a); math.Abs(got-c.want) > .001 {
+			t.Fatalf("%v(%q, %q): expected %v, got %v", c.m, c.b, c.a, c.want, got)
+		}
+
+		if c.m.similarity() && math.Abs(c.m.Distance(c.a, c.b)-(1-c.m.Similarity(c.a, c.b))) > 1e-9 {
+			t.Fatalf("%v(%q, %q): distance %v is not 1 - similarity", c.m, c.a, c.b, c.m.Distance(c.a, c.b))
+		}
+	}
+}
+
+// Searches with each similarity metric, checked against the similarity of every word, which also checks that the
+// bounds used to prune the trie never exceed the similarity of a word below the pruned node.
+func TestSearchSimilarity(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	words := map[string]bool{}
+	for len(words) < 2000 {
+		words[random_word(r, "abcdé", 9)] = true
+	}
+	delete(words, "")
+
+	list := []string{}
+	for w := range words {
+		list = append(list, w)
+	}
+	sort.Strings(list)
+
+	dict, err := Load(strings.NewReader(strings.Join(list, "\n")), Words)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []Metric{JaroWinkler, Dice, Jaccard} {
+		for n := 0; n < 30; n++ {
+			s := random_word(r, "abcdé", 9)
+			limit := r.Float64() / 2
+
+			want := []string{}
+			for _, w := range list {
+				if m.Distance(w, s) <= limit {
+					want = append(want, w)
+				}
+			}
+
+			c, _ := search_lev_context(context.Background(), dict.Node, s, limit, m, nil)
+			got := []string{}
+			for _, v := range c {
+				got = append(got, v.Word)
+				if math.Abs(v.ld[op_distance]-m.Distance(v.Word, s)) > 1e-9 {
+					t.Fatalf("%v %q -> %q: expected distance %v, got %v", m, s, v.Word, m.Distance(v.Word, s), v.ld)
+				}
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("%v search for %q within %v: expected %v, got %v", m, s, limit, want, got)
+			}
+		}
+	}
+}
+
+func TestSimilarityMetric(t *testing.T) {
+	dict := NewDict()
+	for _, m := range []Metric{JaroWinkler, Dice} {
+		dict.Metric = m
+		if r := dict.PartialMatch("speling", .3, 5); len(r) == 0 || r[0].Word != "spelling" {
+			t.Fatalf("%v: expected spelling for speling, got %v", m, r)
+		}
+	}
+
+	r := dict.PartialMatch("speling", .3, 1)
+	metrics := r[0].Metrics()
+	for _, m := range []Metric{JaroWinkler, Dice, Jaccard} {
+		if got, want := metrics[m.String()], m.Similarity("spelling", "speling"); math.Abs(got-want) > 1e-9 {
+			t.Fatalf("expected a %v similarity of %v in the metrics, got %v", m, want, got)
+		}
+	}
+
+	if e := r[0].Edits(); !reflect.DeepEqual(e, []Edit{{Op: Insert, Pos: 3, To: 'l'}}) {
+		t.Fatalf("expected one insertion, got %v", e)
+	}
+}
@@ -0,0 +1,372 @@
+package spell
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"sort"
+	"sync/atomic"
+
+	txt "github.com/hvlck/txt"
+)
+
+// Binary snapshot layout (all integers are little-endian, `uvarint` is encoding/binary's unsigned varint):
+//
+//	magic     "SPEL"
+//	version   uint16
+//	meta      uvarint count, then count × (uvarint key length, key, uvarint value length, value)
+//	nodes     uvarint, number of trie nodes below the root, used to allocate them up front
+//	words     uvarint count, then count × entry
+//	checksum  uint32, CRC-32 (IEEE) of every preceding byte
+//
+// Words are written in byte order, each entry sharing a prefix with the previous word:
+//
+//	shared    uvarint, number of leading bytes shared with the previous word
+//	suffix    uvarint length, bytes
+//	flags     byte, bit 0 set if a frequency follows
+//	frequency float64 bits, uint64
+const (
+	snapshot_magic   = "SPEL"
+	snapshot_version = 1
+
+	snapshot_has_freq = 1 << 0
+)
+
+var (
+	ErrNotSnapshot      = errors.New("spell: not a dictionary snapshot")
+	ErrSnapshotVersion  = errors.New("spell: unsupported snapshot version")
+	ErrSnapshotChecksum = errors.New("spell: snapshot checksum mismatch")
+	ErrSnapshotCorrupt  = errors.New("spell: corrupt snapshot")
+)
+
+// Writer that tracks the number of bytes written and the running checksum.
+type snapshot_writer struct {
+	w   *bufio.Writer
+	crc hash.Hash32
+	n   int64
+	err error
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (s *snapshot_writer) write(b []byte) {
+	if s.err != nil {
+		return
+	}
+
+	n, err := s.w.Write(b)
+	s.crc.Write(b[:n])
+	s.n += int64(n)
+	s.err = err
+}
+
+func (s *snapshot_writer) uvarint(v uint64) {
+	s.write(s.buf[:binary.PutUvarint(s.buf[:], v)])
+}
+
+func (s *snapshot_writer) bytes(b []byte) {
+	s.uvarint(uint64(len(b)))
+	s.write(b)
+}
+
+// WriteTo writes a binary snapshot of the dictionary to `w`: its metadata, every word, and each word's count.
+// Log-probabilities are not stored; they are recomputed when the snapshot is read.
+func (d *Dict) WriteTo(w io.Writer) (int64, error) {
+	s := &snapshot_writer{w: bufio.NewWriter(w), crc: crc32.NewIEEE()}
+
+	s.write([]byte(snapshot_magic))
+	binary.LittleEndian.PutUint16(s.buf[:2], snapshot_version)
+	s.write(s.buf[:2])
+
+	keys := make([]string, 0, len(d.Meta))
+	for k := range d.Meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	s.uvarint(uint64(len(keys)))
+	for _, k := range keys {
+		s.bytes([]byte(k))
+		s.bytes([]byte(d.Meta[k]))
+	}
+
+	count := 0
+	d.Walk(func(string, float64) bool {
+		count++
+		return true
+	})
+	s.uvarint(uint64(count_nodes(d.Node) - 1))
+	s.uvarint(uint64(count))
+
+	prev := ""
+	d.Walk(func(word string, count float64) bool {
+		shared := 0
+		for shared < len(prev) && shared < len(word) && prev[shared] == word[shared] {
+			shared++
+		}
+
+		s.uvarint(uint64(shared))
+		s.bytes([]byte(word[shared:]))
+
+		if math.IsNaN(count) {
+			s.write([]byte{0})
+		} else {
+			s.write([]byte{snapshot_has_freq})
+			binary.LittleEndian.PutUint64(s.buf[:8], math.Float64bits(count))
+			s.write(s.buf[:8])
+		}
+
+		prev = word
+		return s.err == nil
+	})
+
+	binary.LittleEndian.PutUint32(s.buf[:4], s.crc.Sum32())
+	s.write(s.buf[:4])
+
+	if s.err == nil {
+		s.err = s.w.Flush()
+	}
+
+	return s.n, s.err
+}
+
+// Reader that tracks the number of bytes read and the running checksum.
+type snapshot_reader struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+	n   int64
+}
+
+func (s *snapshot_reader) ReadByte() (byte, error) {
+	b, err := s.r.ReadByte()
+	if err == nil {
+		s.crc.Write([]byte{b})
+		s.n++
+	}
+
+	return b, err
+}
+
+func (s *snapshot_reader) full(b []byte) error {
+	n, err := io.ReadFull(s.r, b)
+	s.crc.Write(b[:n])
+	s.n += int64(n)
+
+	return err
+}
+
+func (s *snapshot_reader) uvarint() (uint64, error) {
+	return binary.ReadUvarint(s)
+}
+
+// Reads a length-prefixed byte string, refusing lengths larger than `limit`.
+func (s *snapshot_reader) bytes(limit uint64) ([]byte, error) {
+	l, err := s.uvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	if l > limit {
+		return nil, ErrSnapshotCorrupt
+	}
+
+	b := make([]byte, l)
+	return b, s.full(b)
+}
+
+// upper bounds on lengths and counts in a snapshot, to guard against corrupt length prefixes
+const (
+	snapshot_max_len   = 1 << 20
+	snapshot_max_nodes = 1 << 28
+
+	// nodes allocated at once while reading
+	snapshot_node_chunk = 1 << 12
+)
+
+// Returns the number of nodes in the trie, including `n`.
+func count_nodes(n *txt.Node) int {
+	c := 1
+	for _, v := range n.Kids {
+		c += count_nodes(v)
+	}
+
+	return c
+}
+
+// ReadFrom replaces the contents of the dictionary with a snapshot written by WriteTo.
+// The dictionary is left unchanged if the snapshot is invalid. `r` is read through a buffer, so it may be consumed
+// past the end of the snapshot.
+func (d *Dict) ReadFrom(r io.Reader) (int64, error) {
+	s := &snapshot_reader{r: bufio.NewReader(r), crc: crc32.NewIEEE()}
+	n, meta, err := read_snapshot(s)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return s.n, err
+	}
+
+	d.Node = n
+	d.Meta = meta
+	d.Normalize()
+	return s.n, nil
+}
+
+// ReadSnapshot builds a new dictionary from a snapshot written by Dict.WriteTo.
+func ReadSnapshot(r io.Reader) (*Dict, error) {
+	d := &Dict{}
+	if _, err := d.ReadFrom(r); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func read_snapshot(s *snapshot_reader) (*txt.Node, map[string]string, error) {
+	head := make([]byte, len(snapshot_magic)+2)
+	if err := s.full(head); err != nil {
+		return nil, nil, err
+	}
+
+	if string(head[:len(snapshot_magic)]) != snapshot_magic {
+		return nil, nil, ErrNotSnapshot
+	}
+
+	if binary.LittleEndian.Uint16(head[len(snapshot_magic):]) != snapshot_version {
+		return nil, nil, ErrSnapshotVersion
+	}
+
+	nmeta, err := s.uvarint()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	meta := map[string]string{}
+	for i := uint64(0); i < nmeta; i++ {
+		k, err := s.bytes(snapshot_max_len)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		v, err := s.bytes(snapshot_max_len)
+		if err != nil {
+			return nil, nil, err
+		}
+		meta[string(k)] = string(v)
+	}
+
+	nnodes, err := s.uvarint()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	count, err := s.uvarint()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if nnodes > snapshot_max_nodes {
+		return nil, nil, ErrSnapshotCorrupt
+	}
+
+	// nodes are allocated in chunks rather than one at a time; the count is not checked until the checksum is read, so
+	// chunks are only allocated as entries need them
+	var nodes []txt.Node
+	next_node := func(rn rune) *txt.Node {
+		if len(nodes) == 0 {
+			if nnodes == 0 {
+				return new_node(rn)
+			}
+
+			size := nnodes
+			if size > snapshot_node_chunk {
+				size = snapshot_node_chunk
+			}
+			nodes = make([]txt.Node, size)
+			nnodes -= size
+		}
+
+		n := &nodes[0]
+		nodes = nodes[1:]
+		// terminal nodes never have children
+		if rn != '*' {
+			n.Kids = make(map[rune]*txt.Node)
+		}
+		n.Character = rn
+		n.Id = atomic.AddUint32(&node_id, 1)
+		return n
+	}
+
+	root := txt.NewTrie()
+	// path[i] is the node reached after the first i bytes of the previous word; since words are sorted, each entry
+	// only needs new nodes for the bytes after the shared prefix
+	path := []*txt.Node{root}
+	word := []byte{}
+	num := make([]byte, 8)
+	for i := uint64(0); i < count; i++ {
+		shared, err := s.uvarint()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if shared > uint64(len(word)) {
+			return nil, nil, ErrSnapshotCorrupt
+		}
+
+		suffix, err := s.bytes(snapshot_max_len)
+		if err != nil {
+			return nil, nil, err
+		}
+		word = append(word[:shared], suffix...)
+
+		flags, err := s.ReadByte()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		count := math.NaN()
+		if flags&snapshot_has_freq != 0 {
+			if err := s.full(num); err != nil {
+				return nil, nil, err
+			}
+			count = math.Float64frombits(binary.LittleEndian.Uint64(num))
+		}
+
+		if len(word) == 0 || bytes.IndexByte(suffix, '*') != -1 {
+			return nil, nil, ErrSnapshotCorrupt
+		}
+
+		path = path[:shared+1]
+		n := path[shared]
+		for _, c := range suffix {
+			next, ok := n.Kids[rune(c)]
+			if !ok {
+				next = next_node(rune(c))
+				n.Kids[rune(c)] = next
+			}
+			n = next
+			path = append(path, n)
+		}
+
+		end := next_node('*')
+		end.Done = true
+		end.Data = encode_freq(count, 0)
+		n.Kids['*'] = end
+	}
+
+	sum := s.crc.Sum32()
+	if _, err := io.ReadFull(s.r, num[:4]); err != nil {
+		return nil, nil, err
+	}
+
+	if binary.LittleEndian.Uint32(num[:4]) != sum {
+		return nil, nil, ErrSnapshotChecksum
+	}
+	s.n += 4
+
+	return root, meta, nil
+}
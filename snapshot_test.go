@@ -0,0 +1,119 @@
+package spell
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	d, err := Load(strings.NewReader("apple,10\napples,2.5\nbanana,3\ncafé,1e-3\n"), CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Insert("zebra", nil)
+	d.Normalize()
+	d.Meta = map[string]string{"source": "test", "language": "en"}
+
+	buf := bytes.Buffer{}
+	n, err := d.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != int64(buf.Len()) {
+		t.Fatalf("WriteTo reported %v bytes, wrote %v", n, buf.Len())
+	}
+
+	r := &Dict{}
+	if m, err := r.ReadFrom(bytes.NewReader(buf.Bytes())); err != nil || m != n {
+		t.Fatalf("ReadFrom read %v of %v bytes: %v", m, n, err)
+	}
+
+	want := map[string]string{"apple": "10", "apples": "2.5", "banana": "3", "café": "0.001", "zebra": "NaN"}
+	count := 0
+	r.Walk(func(word string, c float64) bool {
+		count++
+		if f, ok := want[word]; !ok || f != fmt.Sprint(c) {
+			t.Fatalf("unexpected entry %q (%v)", word, c)
+		}
+
+		_, expected, _ := d.Frequency(word)
+		if _, logp, _ := r.Frequency(word); logp != expected {
+			t.Fatalf("expected log-probability %v for %q, got %v", expected, word, logp)
+		}
+		return true
+	})
+
+	if count != len(want) {
+		t.Fatalf("expected %v words, got %v", len(want), count)
+	}
+
+	if r.Meta["source"] != "test" || r.Meta["language"] != "en" {
+		t.Fatalf("metadata not restored: %v", r.Meta)
+	}
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	d := NewDict()
+	buf := bytes.Buffer{}
+	if _, err := d.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+
+	corrupt := append([]byte{}, b...)
+	corrupt[len(corrupt)/2] ^= 0xff
+
+	version := append([]byte{}, b...)
+	version[4] = 99
+
+	// a huge node count in an otherwise empty snapshot, which must not be allocated before it is verified
+	huge := make([]byte, binary.MaxVarintLen64)
+	huge = append([]byte("SPEL\x01\x00\x00"), huge[:binary.PutUvarint(huge, snapshot_max_nodes)]...)
+	huge = append(huge, 0)
+
+	cases := []struct {
+		input []byte
+		err   error
+	}{
+		{[]byte("a\naa\naaa\n"), ErrNotSnapshot},
+		{version, ErrSnapshotVersion},
+		{corrupt, nil},
+		{b[:len(b)-10], nil},
+		{huge, io.ErrUnexpectedEOF},
+	}
+
+	for i, c := range cases {
+		_, err := ReadSnapshot(bytes.NewReader(c.input))
+		if err == nil || (c.err != nil && !errors.Is(err, c.err)) {
+			t.Fatalf("case %v: expected %v, got %v", i, c.err, err)
+		}
+	}
+}
+
+func BenchmarkLoadWords(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := Load(bytes.NewReader(dict_file), Words); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadSnapshot(b *testing.B) {
+	buf := bytes.Buffer{}
+	if _, err := NewDict().WriteTo(&buf); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadSnapshot(bytes.NewReader(buf.Bytes())); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
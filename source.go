@@ -0,0 +1,208 @@
+package spell
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	txt "github.com/hvlck/txt"
+)
+
+// CandidateSource generates candidate corrections: every word within `limit` edit distances of `s`, unweighted and in
+// any order. Candidates are ranked separately by Match, so any index can back a search, e.g. the trie of a *Dict, a
+// *MappedDict, *SymSpell or *BKTree index, a Linear word list, a Remote service, or a stack of *Layers.
+type CandidateSource interface {
+	Candidates(s string, limit float64) []Correction
+}
+
+// ErrUnsupportedMetric is returned when indexing a dictionary whose metric the index cannot search with.
+var ErrUnsupportedMetric = errors.New("spell: metric not supported by this index")
+
+// CandidateFunc adapts an ordinary function to the CandidateSource interface.
+type CandidateFunc func(s string, limit float64) []Correction
+
+func (f CandidateFunc) Candidates(s string, limit float64) []Correction {
+	return f(s, limit)
+}
+
+// Sources with an ignore list.
+type ignorer interface {
+	ignores(s string) bool
+}
+
+// Sources with a block list.
+type blocker interface {
+	blocks(s string) bool
+}
+
+// Sources that can stop a search early, returning the candidates found so far and whether they stopped.
+type context_source interface {
+	candidates_context(ctx context.Context, s string, limit float64) ([]Correction, bool)
+}
+
+// Candidates generated by `src` until `ctx` is done. Sources that cannot be stopped run to completion, unless `ctx`
+// is already done when the search starts.
+func candidates(ctx context.Context, src CandidateSource, s string, limit float64) ([]Correction, bool) {
+	if c, ok := src.(context_source); ok {
+		return c.candidates_context(ctx, s, limit)
+	}
+
+	if ctx.Err() != nil {
+		return []Correction{}, true
+	}
+
+	return src.Candidates(s, limit), false
+}
+
+// Match weighs the candidates `src` generates for `s` within `target` edit distances, and returns the `max` highest
+// weighted. If `src` has an ignore list containing `s`, `s` is returned on its own as an exact match.
+func Match(src CandidateSource, s string, target float64, max int) []Correction {
+	c, _ := MatchContext(context.Background(), src, s, target, max)
+	return c
+}
+
+// MatchContext is Match, stopping the search once `ctx` is done (e.g. at its deadline). The best corrections among the
+// candidates found until then are returned, and `partial` reports whether the search was stopped before it finished.
+// Dictionaries, mapped dictionaries, personal dictionaries and layers stop within microseconds; other sources finish
+// the search they started.
+func MatchContext(ctx context.Context, src CandidateSource, s string, target float64, max int) (c []Correction, partial bool) {
+	if i, ok := src.(ignorer); ok && i.ignores(s) {
+		return ignored(s), false
+	}
+
+	f, partial := candidates(ctx, src, s, target)
+	return rank(f, s, target, max), partial
+}
+
+// Candidates returns all words in the dictionary within `limit` edit distances of `s`. Blocked words are never
+// candidates.
+func (d *Dict) Candidates(s string, limit float64) []Correction {
+	c, _ := d.candidates_context(context.Background(), s, limit)
+	return c
+}
+
+func (d *Dict) candidates_context(ctx context.Context, s string, limit float64) ([]Correction, bool) {
+	var c []Correction
+	var partial bool
+	if d.Parallelism == 0 || d.Parallelism == 1 {
+		c, partial = search_lev_context(ctx, d.Node, s, limit, d.Metric, d.Costs)
+	} else {
+		c, partial = search_lev_parallel(ctx, d.Node, s, limit, d.Metric, d.Costs, d.Parallelism)
+	}
+
+	return d.Block.filter(c), partial
+}
+
+func (d *Dict) ignores(s string) bool {
+	return d.Ignore.Match(s)
+}
+
+func (d *Dict) blocks(s string) bool {
+	return d.Block.Match(s)
+}
+
+// Bare trie, e.g. one built with txt.Insert.
+type trie struct {
+	*txt.Node
+}
+
+func (t trie) Candidates(s string, limit float64) []Correction {
+	return search_lev(t.Node, s, limit)
+}
+
+func (t trie) candidates_context(ctx context.Context, s string, limit float64) ([]Correction, bool) {
+	return search_lev_context(ctx, t.Node, s, limit, OSA, nil)
+}
+
+// Linear is a plain word list, searched by computing the distance to every word. It needs no index, so it suits small
+// or frequently rebuilt lists; its words have no frequency data.
+type Linear []string
+
+// Candidates returns all words in the list within `limit` edit distances of `s`, in list order. Distance rows are
+// reused for the prefix a word shares with the one before it, so sorted lists are searched fastest.
+func (l Linear) Candidates(s string, limit float64) []Correction {
+	res := []Correction{}
+	r := new_lev_rows(s, OSA, nil)
+	// length of the path with computed rows, and of the shortest prefix of it too far from `s`, if any
+	valid, dead := 0, -1
+	for _, w := range l {
+		i := 0
+		for i < valid && i < len(w) && r.path[i] == w[i] {
+			i++
+		}
+
+		if dead >= 0 && i >= dead {
+			continue
+		}
+		dead = -1
+
+		for ; i < len(w); i++ {
+			if r.push(i, w[i]) > limit {
+				dead = i + 1
+				break
+			}
+		}
+
+		if dead >= 0 {
+			valid = dead
+			continue
+		}
+
+		valid = len(w)
+		if len(w) > 0 && r.distance(len(w)) <= limit {
+			res = append(res, r.correction(len(w), nil))
+		}
+	}
+
+	return res
+}
+
+// Remote adapts a lookup in another process, such as a shared spelling service, into a CandidateSource.
+// Lookup returns the candidate words for a query with their log-probabilities; distances are computed locally, and
+// words further than the limit are dropped.
+type Remote struct {
+	Lookup func(s string, limit float64) (map[string]float64, error)
+	// Called with errors returned by Lookup, which otherwise produce no candidates. Optional.
+	OnError func(err error)
+}
+
+// Candidates returns the words Lookup finds for `s`, within `limit` edit distances.
+func (r Remote) Candidates(s string, limit float64) []Correction {
+	res := []Correction{}
+	words, err := r.Lookup(s, limit)
+	if err != nil {
+		if r.OnError != nil {
+			r.OnError(err)
+		}
+		return res
+	}
+
+	for w, logp := range words {
+		if lev := operations(OSA, nil, w, s); lev[0] <= limit {
+			c := new_correction(w, lev, nil)
+			c.LogProb = logp
+			res = append(res, c)
+		}
+	}
+
+	return res
+}
+
+// Corrections for `word` found by `src` within `lim` edit distances, as returned by Correct. Candidates are visited in
+// byte order, and each match lowers the limit for the matches after it.
+func correct(src CandidateSource, word string, lim float64) map[string]float64 {
+	candidates := src.Candidates(word, lim)
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Word < candidates[j].Word
+	})
+
+	matches := map[string]float64{}
+	for _, c := range candidates {
+		if c.ld[0] <= lim {
+			matches[c.Word] = c.ld[0]
+			lim = c.ld[0]
+		}
+	}
+
+	return matches
+}
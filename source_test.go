@@ -0,0 +1,103 @@
+package spell
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCandidateSources(t *testing.T) {
+	dict, err := Load(strings.NewReader("cat\ncar\ncart\ncast\ndog\n"), Words)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote := Remote{Lookup: func(s string, limit float64) (map[string]float64, error) {
+		return map[string]float64{"cat": -1, "car": -1, "cart": -1, "cast": -1, "dog": -1}, nil
+	}}
+
+	sources := map[string]CandidateSource{
+		"dict":     dict,
+		"trie":     trie{dict.Node},
+		"symspell": must(NewSymSpell(dict, 2)),
+		"bktree":   must(NewBKTree(dict)),
+		"linear":   Linear{"cat", "car", "cart", "cast", "dog"},
+		"remote":   remote,
+		"func":     CandidateFunc(dict.Candidates),
+	}
+
+	for name, src := range sources {
+		got := []string{}
+		for _, c := range src.Candidates("cst", 1) {
+			got = append(got, c.Word)
+		}
+		sort.Strings(got)
+
+		if want := []string{"cast", "cat"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("%v: expected %v, got %v", name, want, got)
+		}
+	}
+}
+
+func TestLinear(t *testing.T) {
+	// linear lists have no frequency data, so only words and distances are compared
+	sorted := func(c []Correction) []string {
+		r := []string{}
+		for _, v := range c {
+			r = append(r, fmt.Sprintf("%v/%v", v.Word, v.ld))
+		}
+		sort.Strings(r)
+		return r
+	}
+
+	for _, s := range []string{"", "wat", "korrectud", "peotryy"} {
+		for _, limit := range []float64{0, 1, 2} {
+			got, want := sorted(dict.Candidates(s, limit)), sorted(search_lev(d.Node, s, limit))
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("%q within %v: expected %v candidates, got %v", s, limit, len(want), len(got))
+			}
+		}
+	}
+
+	// unsorted lists, and words pruned after a shared prefix
+	if r := sorted(Linear{"cats", "cat", "zzz", "zzzz", "at"}.Candidates("cat", 1)); len(r) != 3 {
+		t.Fatalf("expected cats, cat and at, got %v", r)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	dict, err := Load(strings.NewReader("cat\ncar\ncart\n"), Words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict.Ignore.Add("cst")
+
+	if r := Match(dict, "cst", 1, 5); len(r) != 1 || r[0].Word != "cst" {
+		t.Fatalf("expected ignored word to be returned as-is, got %v", r)
+	}
+
+	// ignore lists belong to the source, so a plain list of the same words has none
+	if r := Match(Linear{"cat", "car", "cart"}, "cst", 1, 5); r[0].Word != "cat" {
+		t.Fatalf("expected cat, got %v", r)
+	}
+}
+
+func TestRemoteErrors(t *testing.T) {
+	failed := errors.New("unavailable")
+	var reported error
+	r := Remote{
+		Lookup:  func(s string, limit float64) (map[string]float64, error) { return nil, failed },
+		OnError: func(err error) { reported = err },
+	}
+
+	if c := r.Candidates("cat", 1); len(c) != 0 {
+		t.Fatalf("expected no candidates, got %v", c)
+	}
+
+	if reported != failed {
+		t.Fatalf("expected %v to be reported, got %v", failed, reported)
+	}
+}
@@ -0,0 +1,92 @@
+package spell
+
+import (
+	"context"
+	"sort"
+
+	txt "github.com/hvlck/txt"
+)
+
+// Update is sent by a streaming search each time it finds a correction.
+type Update struct {
+	// Correction just found, weighed.
+	Correction Correction
+	// Best corrections found so far, best first, in the same order as PartialMatch returns them. At most `max` are
+	// kept, or 100 if `max` is negative. Each update has its own copy.
+	Best []Correction
+}
+
+// Number of corrections kept in Update.Best when a streaming search is not limited, since every update copies them.
+const stream_best = 100
+
+// Stream searches the given trie in the same way as PartialMatch, sending an update on the returned channel for
+// every correction within `target` edit distances of `s` as soon as it is found, so results can be shown before the
+// search finishes. The last update holds the same corrections PartialMatch would return, or the first 100 of them if
+// `max` is negative. The channel is closed once the search is done; to stop it early, cancel `ctx` and stop reading.
+func Stream(ctx context.Context, n *txt.Node, s string, target float64, max int) <-chan Update {
+	return stream(ctx, n, nil, s, target, max)
+}
+
+// Stream is the streaming equivalent of the dictionary's PartialMatch, honoring its ignore and block lists. Searches
+// are never split across goroutines, whatever the dictionary's Parallelism.
+func (d *Dict) Stream(ctx context.Context, s string, target float64, max int) <-chan Update {
+	return stream(ctx, d.Node, d, s, target, max)
+}
+
+// Streams the search of `n`, skipping words blocked by `d` and answering words ignored by it, if set.
+func stream(ctx context.Context, n *txt.Node, d *Dict, s string, target float64, max int) <-chan Update {
+	ch := make(chan Update)
+
+	go func() {
+		defer close(ch)
+
+		if d != nil && d.ignores(s) {
+			c := ignored(s)
+			select {
+			case ch <- Update{Correction: c[0], Best: c}:
+			case <-ctx.Done():
+			}
+			return
+		}
+
+		if n == nil {
+			return
+		}
+
+		if max < 0 {
+			max = stream_best
+		}
+
+		// best corrections so far, best first, kept sorted as they are found
+		best := make([]Correction, 0, min(max, stream_best))
+		metric, costs := OSA, (*Costs)(nil)
+		if d != nil {
+			metric, costs = d.Metric, d.Costs
+		}
+
+		r := new_lev_rows(s, metric, costs)
+		r.done = ctx.Done()
+		r.walk_trie(n, 0, target, func(c Correction) {
+			if r.stopped || (d != nil && d.Block.Match(c.Word)) {
+				return
+			}
+
+			c.weigh(s)
+			if i := sort.Search(len(best), func(i int) bool { return c.better(&best[i]) }); i < max {
+				if len(best) < max {
+					best = append(best, Correction{})
+				}
+				copy(best[i+1:], best[i:])
+				best[i] = c
+			}
+
+			select {
+			case ch <- Update{Correction: c, Best: append([]Correction{}, best...)}:
+			case <-ctx.Done():
+				r.stopped = true
+			}
+		})
+	}()
+
+	return ch
+}
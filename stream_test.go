@@ -0,0 +1,89 @@
+package spell
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	var last Update
+	count := 0
+	for u := range Stream(context.Background(), d.Node, "speling", 2, 10) {
+		count++
+		if len(u.Best) > 10 || len(u.Best) > count {
+			t.Fatalf("unexpected running view after %v updates: %v", count, u.Best)
+		}
+		last = u
+	}
+
+	if want := PartialMatch(d.Node, "speling", 2, 10); !reflect.DeepEqual(last.Best, want) {
+		t.Fatalf("expected final view %v, got %v", want, last.Best)
+	}
+
+	if count != len(search_lev(d.Node, "speling", 2)) {
+		t.Fatalf("expected an update for every candidate, got %v", count)
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := Stream(ctx, d.Node, strings.Repeat("qzx", 10), 30, 10)
+	<-ch
+	cancel()
+
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("stream not closed after cancellation")
+		}
+	}
+}
+
+func TestDictStream(t *testing.T) {
+	dict, err := Load(strings.NewReader("duck\nduct\ndusk\n"), Words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict.Block.Add("duct")
+	dict.Ignore.Add("dxck")
+
+	for u := range dict.Stream(context.Background(), "duck", 1, 5) {
+		if u.Correction.Word == "duct" {
+			t.Fatal("blocked word streamed")
+		}
+	}
+
+	u := <-dict.Stream(context.Background(), "dxck", 1, 5)
+	if len(u.Best) != 1 || u.Best[0].Word != "dxck" {
+		t.Fatalf("expected ignored word to be streamed as-is, got %v", u.Best)
+	}
+}
+
+func TestStreamUnlimited(t *testing.T) {
+	var last Update
+	count := 0
+	for u := range Stream(context.Background(), d.Node, "tesk", 3, -1) {
+		count++
+		if len(u.Best) > stream_best {
+			t.Fatalf("expected at most %v corrections in the running view, got %v", stream_best, len(u.Best))
+		}
+		last = u
+	}
+
+	want := PartialMatch(d.Node, "tesk", 3, -1)
+	if count != len(want) || len(want) <= stream_best {
+		t.Fatalf("expected an update for each of more than %v candidates, got %v of %v", stream_best, count, len(want))
+	}
+
+	if !reflect.DeepEqual(last.Best, want[:stream_best]) {
+		t.Fatalf("expected final view to be the first %v corrections, got %v", stream_best, last.Best)
+	}
+}
@@ -0,0 +1,184 @@
+package spell
+
+import (
+	"hash/fnv"
+	"math"
+	"unicode/utf8"
+
+	txt "github.com/hvlck/txt"
+)
+
+// Number of leading runes of each word that are indexed by a SymSpell index. Longer words are still found, since
+// candidates are always checked against the whole word, but their deletions are not stored.
+const symspell_prefix = 7
+
+// SymSpell is a symmetric delete index over the words of a dictionary: every variant of a word with up to `distance`
+// characters deleted points back to the word. A search deletes characters from the query in the same way, so the
+// words within `distance` edits of a query are exactly those sharing a variant with it, and finding them takes a few
+// map lookups instead of a trie walk. Candidates are checked with the dictionary's metric, so a SymSpell index returns
+// the same corrections as its dictionary, as long as searches stay within its distance. Only the OSA and Levenshtein
+// metrics can be indexed: the others find words that share no variant within the distance.
+//
+// The index is a snapshot: words inserted into or removed from the dictionary afterwards are not reflected in it, and
+// neither is a change of its metric, while the dictionary's ignore and block lists are always honored. A SymSpell
+// index can be used as the Source of a Layer, and is safe for concurrent use.
+type SymSpell struct {
+	// largest edit distance searches can find
+	distance int
+	// metric of the dictionary when it was indexed
+	metric Metric
+
+	dict  *Dict
+	words []string
+	// frequency data of each word
+	data [][]byte
+	// hash of each variant to the indices of the words it was derived from; colliding variants only add candidates,
+	// which are removed when checked
+	deletes map[uint64][]uint32
+}
+
+// NewSymSpell indexes the words of `d`, for searches up to `distance` edits. The index size grows quickly with the
+// distance; a distance of 2 covers most spelling mistakes. ErrUnsupportedMetric is returned if `d` uses a metric
+// other than OSA or Levenshtein.
+func NewSymSpell(d *Dict, distance int) (*SymSpell, error) {
+	if d.Metric != OSA && d.Metric != Levenshtein {
+		return nil, ErrUnsupportedMetric
+	}
+
+	idx := &SymSpell{distance: distance, metric: d.Metric, dict: d, deletes: map[uint64][]uint32{}}
+
+	variants := map[string]bool{}
+	walk_terminals(d.Node, nil, func(word []byte, data []byte) {
+		id := uint32(len(idx.words))
+		idx.words = append(idx.words, string(word))
+		idx.data = append(idx.data, data)
+
+		for k := range variants {
+			delete(variants, k)
+		}
+		symspell_deletes(string(word), distance, variants)
+
+		for v := range variants {
+			h := symspell_hash(v)
+			idx.deletes[h] = append(idx.deletes[h], id)
+		}
+	})
+
+	return idx, nil
+}
+
+// Calls `fn` for every word in the trie and the data of its terminal node, in no particular order.
+func walk_terminals(n *txt.Node, prefix []byte, fn func(word []byte, data []byte)) {
+	for rn, v := range n.Kids {
+		if v.Done && len(v.Kids) == 0 {
+			if len(prefix) > 0 {
+				fn(prefix, v.Data)
+			}
+			continue
+		}
+
+		walk_terminals(v, append(prefix, byte(rn)), fn)
+	}
+}
+
+// Adds every variant of the indexed prefix of `s` with up to `distance` runes deleted, including the prefix itself, to
+// `variants`.
+func symspell_deletes(s string, distance int, variants map[string]bool) {
+	for i, n := 0, 0; i < len(s); n++ {
+		if n == symspell_prefix {
+			s = s[:i]
+			break
+		}
+
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+
+	level := []string{s}
+	variants[s] = true
+	for d := 0; d < distance; d++ {
+		next := []string{}
+		for _, v := range level {
+			for i, size := 0, 0; i < len(v); i += size {
+				_, size = utf8.DecodeRuneInString(v[i:])
+				del := v[:i] + v[i+size:]
+				if !variants[del] {
+					variants[del] = true
+					next = append(next, del)
+				}
+			}
+		}
+		level = next
+	}
+}
+
+func symspell_hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// Distance returns the largest edit distance the index can search. Larger limits are reduced to it.
+func (idx *SymSpell) Distance() int {
+	return idx.distance
+}
+
+// Candidates returns all words within `limit` edit distances of `s`, with `limit` reduced to the distance of the index.
+// Blocked words are skipped.
+func (idx *SymSpell) Candidates(s string, limit float64) []Correction {
+	res := []Correction{}
+	if limit < 0 {
+		return res
+	}
+
+	distance := idx.distance
+	if limit < float64(distance) {
+		distance = int(math.Floor(limit))
+	}
+
+	variants := map[string]bool{}
+	symspell_deletes(s, distance, variants)
+
+	seen := map[uint32]bool{}
+	for v := range variants {
+		for _, id := range idx.deletes[symspell_hash(v)] {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			word := idx.words[id]
+			if abs(utf8.RuneCountInString(word)-utf8.RuneCountInString(s)) > distance {
+				continue
+			}
+
+			if lev := operations(idx.metric, nil, word, s); lev[0] <= float64(distance) {
+				c := new_correction(word, lev, idx.data[id])
+				c.metric = idx.metric
+				res = append(res, c)
+			}
+		}
+	}
+
+	return idx.dict.Block.filter(res)
+}
+
+// Version returns a number that changes whenever the ignore or block lists of the indexed dictionary are edited. Words
+// inserted into or removed from the dictionary do not change it, since the index does not see them.
+func (idx *SymSpell) Version() uint64 {
+	return idx.dict.Ignore.version() + idx.dict.Block.version()
+}
+
+func (idx *SymSpell) ignores(s string) bool {
+	return idx.dict.Ignore.Match(s)
+}
+
+func (idx *SymSpell) blocks(s string) bool {
+	return idx.dict.Block.Match(s)
+}
+
+// PartialMatch returns the `max` best corrections for `s` within `target` edit distances, in the same way as the
+// dictionary's PartialMatch. `target` is reduced to the distance of the index.
+func (idx *SymSpell) PartialMatch(s string, target float64, max int) []Correction {
+	return Match(idx, s, target, max)
+}
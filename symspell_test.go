@@ -0,0 +1,129 @@
+package spell
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var symspell = must(NewSymSpell(d, 2))
+
+func TestSymSpell(t *testing.T) {
+	sorted := func(c []Correction) []Correction {
+		sort.Slice(c, func(i, j int) bool { return c[i].Word < c[j].Word })
+		return c
+	}
+
+	queries := []string{"", "a", "wat", "tesk", "speling", "korrectud", "bycycle", "inconvient", "arrainged", "peotryy", "recieve", "abcdefghijkl", "internationalizaton"}
+	for _, s := range queries {
+		for _, limit := range []float64{0, 1, 1.5, 2} {
+			want := sorted(search_lev(d.Node, s, limit))
+			if got := sorted(symspell.Candidates(s, limit)); !reflect.DeepEqual(got, want) {
+				t.Fatalf("%q within %v: expected %v, got %v", s, limit, candidate_words(want), candidate_words(got))
+			}
+		}
+	}
+
+	// limits are reduced to the distance of the index
+	if got, want := len(symspell.Candidates("korrectud", 3)), len(search_lev(d.Node, "korrectud", 2)); got != want {
+		t.Fatalf("expected %v corrections, got %v", want, got)
+	}
+}
+
+func TestSymSpellLists(t *testing.T) {
+	dict, err := Load(strings.NewReader("cat\ncar\ncart\n"), Words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict.Block.Add("car")
+	dict.Ignore.Add("cst")
+
+	words := func(c []Correction) []string {
+		r := []string{}
+		for _, v := range c {
+			r = append(r, v.Word)
+		}
+		sort.Strings(r)
+		return r
+	}
+
+	idx := must(NewSymSpell(dict, 1))
+	if r := words(idx.Candidates("cat", 1)); !reflect.DeepEqual(r, []string{"cart", "cat"}) {
+		t.Fatalf("expected blocked words to be removed, got %v", r)
+	}
+
+	// lists are read when searching
+	dict.Block.Remove("car")
+	if r := words(idx.Candidates("cat", 1)); !reflect.DeepEqual(r, []string{"car", "cart", "cat"}) {
+		t.Fatalf("expected unblocked words to be found, got %v", r)
+	}
+
+	if r := idx.PartialMatch("cst", 1, 5); len(r) != 1 || r[0].Word != "cst" {
+		t.Fatalf("expected ignored word to be returned as-is, got %v", r)
+	}
+}
+
+func TestSymSpellMetric(t *testing.T) {
+	dict, err := Load(strings.NewReader("cat\nact\ncart\nscat\ntac\n"), Words)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sorted := func(c []Correction) []Correction {
+		sort.Slice(c, func(i, j int) bool { return c[i].Word < c[j].Word })
+		return c
+	}
+
+	dict.Metric = Levenshtein
+	idx := must(NewSymSpell(dict, 2))
+	for _, s := range []string{"cat", "atc", "cta"} {
+		for _, limit := range []float64{1, 2} {
+			if got, want := sorted(idx.Candidates(s, limit)), sorted(dict.Candidates(s, limit)); !reflect.DeepEqual(got, want) {
+				t.Fatalf("%q within %v: expected %v, got %v", s, limit, candidate_words(want), candidate_words(got))
+			}
+		}
+	}
+
+	// the index keeps the metric it was built with
+	dict.Metric = Damerau
+	if r := idx.Candidates("act", 1); len(r) != 1 || r[0].Word != "act" {
+		t.Fatalf("expected only act within 1 levenshtein edit of act, got %v", candidate_words(r))
+	}
+
+	for _, m := range []Metric{Damerau, Keyboard, JaroWinkler, Dice, Jaccard} {
+		dict.Metric = m
+		if _, err := NewSymSpell(dict, 2); err != ErrUnsupportedMetric {
+			t.Fatalf("%v: expected ErrUnsupportedMetric, got %v", m, err)
+		}
+	}
+}
+
+// go test -bench SymSpell -benchmem
+//
+//	BenchmarkSymSpell/wat              3226      732210 ns/op    462434 B/op    6843 allocs/op
+//	BenchmarkSymSpell/wat/trie         1351     1889747 ns/op    367977 B/op    5592 allocs/op
+//	BenchmarkSymSpell/korrectud       16260       74464 ns/op     44592 B/op     484 allocs/op
+//	BenchmarkSymSpell/korrectud/trie   1615      783357 ns/op      3776 B/op      41 allocs/op
+//	BenchmarkNewSymSpell                  1  1349939104 ns/op 214759008 B/op 4230248 allocs/op
+func BenchmarkSymSpell(b *testing.B) {
+	for _, s := range []string{"wat", "korrectud"} {
+		b.Run(s, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				symspell.Candidates(s, 2)
+			}
+		})
+
+		b.Run(s+"/trie", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				search_lev(d.Node, s, 2)
+			}
+		})
+	}
+}
+
+func BenchmarkNewSymSpell(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		must(NewSymSpell(d, 2))
+	}
+}